@@ -0,0 +1,132 @@
+package hypercredscan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// archiveSeparator joins an archive's path and the path of an entry inside it,
+// e.g. "dist/app.jar!config/application.properties".
+const archiveSeparator = "!"
+
+// maxArchiveDepth bounds how many archives-within-archives are expanded.
+const maxArchiveDepth = 3
+
+// isTarFile reports whether content looks like a POSIX tar archive.
+func isTarFile(content []byte) bool {
+	return len(content) > 262 && bytes.Equal(content[257:262], []byte("ustar"))
+}
+
+func isGzipFile(content []byte) bool {
+	return len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b
+}
+
+// isArchive reports whether content is an archive expandArchive understands.
+func isArchive(content []byte) bool {
+	return IsZipFile(content) || isTarFile(content) || isGzipFile(content)
+}
+
+// expandArchive returns the regular files contained in blob, recursing into
+// nested archives up to maxArchiveDepth. Entries larger than maxSize are
+// skipped; a maxSize of zero disables the limit.
+func expandArchive(blob *findings.Blob, maxSize int64, depth int) ([]*findings.Blob, error) {
+	if depth >= maxArchiveDepth {
+		return nil, nil
+	}
+
+	var entries []*findings.Blob
+	add := func(name string, r io.Reader, size int64) error {
+		if maxSize > 0 {
+			if size > maxSize {
+				return nil
+			}
+			// Declared sizes can lie, so bound the read as well.
+			r = io.LimitReader(r, maxSize+1)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if maxSize > 0 && int64(len(content)) > maxSize {
+			return nil
+		}
+		entry := &findings.Blob{Path: blob.Path + archiveSeparator + name, Content: content}
+		if isArchive(content) {
+			nested, err := expandArchive(entry, maxSize, depth+1)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, nested...)
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+
+	switch {
+	case IsZipFile(blob.Content):
+		zr, err := zip.NewReader(bytes.NewReader(blob.Content), int64(len(blob.Content)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = add(f.Name, rc, int64(f.UncompressedSize64))
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case isGzipFile(blob.Content):
+		gr, err := gzip.NewReader(bytes.NewReader(blob.Content))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		var r io.Reader = gr
+		if maxSize > 0 {
+			r = io.LimitReader(gr, maxSize+1)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if maxSize > 0 && int64(len(content)) > maxSize {
+			return nil, nil
+		}
+		if isTarFile(content) {
+			return expandArchive(&findings.Blob{Path: blob.Path, Content: content}, maxSize, depth)
+		}
+		return []*findings.Blob{{Path: blob.Path, Content: content}}, nil
+	case isTarFile(blob.Content):
+		tr := tar.NewReader(bytes.NewReader(blob.Content))
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := add(hdr.Name, tr, hdr.Size); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,303 @@
+package hypercredscan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	baselineVersion = 1
+	// baselineCheckProvider and baselineCheckSecret are fingerprinted to
+	// produce the baseline's fingerprint check, which detects a baseline being
+	// used with a different fingerprint key than it was created with.
+	baselineCheckProvider = "HYPERCREDSCAN_BASELINE"
+	baselineCheckSecret   = "fingerprint-check"
+)
+
+// BaselineEntry acknowledges a known finding. Entries match findings by
+// fingerprint and provider, and by path unless Path is empty, in which case
+// the secret is acknowledged wherever it appears. Path is relative to the
+// baseline's root.
+type BaselineEntry struct {
+	Fingerprint    string    `json:"fingerprint"`
+	Provider       string    `json:"provider"`
+	Path           string    `json:"path,omitempty"`
+	AcknowledgedBy string    `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+	Reason         string    `json:"reason,omitempty"`
+}
+
+type baselineKey struct {
+	fingerprint string
+	provider    string
+	path        string
+}
+
+func (e *BaselineEntry) key() baselineKey {
+	return baselineKey{fingerprint: e.Fingerprint, provider: e.Provider, path: e.Path}
+}
+
+type baselineFile struct {
+	Version          int              `json:"version"`
+	FingerprintCheck string           `json:"fingerprint_check,omitempty"`
+	Entries          []*BaselineEntry `json:"entries"`
+}
+
+// Baseline is a set of acknowledged findings. Used as a findings.Filter it
+// suppresses findings that match an entry, so only new findings are reported,
+// and remembers which entries matched so stale ones can be reported after a
+// scan. A Baseline is safe for concurrent use.
+type Baseline struct {
+	root string
+
+	mu               sync.Mutex
+	fingerprintCheck string
+	entries          map[baselineKey]*BaselineEntry
+	seen             map[baselineKey]bool
+}
+
+// NewBaseline returns an empty baseline for findings fingerprinted by
+// fingerprint, typically Scanner.Fingerprint. Entry paths are relative to
+// root, the repository root being scanned, so an entry means the same
+// whichever directory the scanner runs in. An empty root records and matches
+// paths as reported.
+func NewBaseline(fingerprint func(provider string, secret []byte) string, root string) *Baseline {
+	return &Baseline{
+		root:             root,
+		fingerprintCheck: fingerprint(baselineCheckProvider, []byte(baselineCheckSecret)),
+		entries:          make(map[baselineKey]*BaselineEntry),
+		seen:             make(map[baselineKey]bool),
+	}
+}
+
+// LoadBaseline reads the baseline at path, with entry paths relative to root
+// as for NewBaseline. It fails if the baseline was created with a different
+// fingerprint key, as none of its entries could match.
+func LoadBaseline(path string, fingerprint func(provider string, secret []byte) string, root string) (*Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ReadBaseline(f, fingerprint, root)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// ReadBaseline reads a baseline written by Baseline.Write, with entry paths
+// relative to root as for NewBaseline.
+func ReadBaseline(r io.Reader, fingerprint func(provider string, secret []byte) string, root string) (*Baseline, error) {
+	var file baselineFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d", file.Version)
+	}
+	b := NewBaseline(fingerprint, root)
+	if file.FingerprintCheck != "" && file.FingerprintCheck != b.fingerprintCheck {
+		return nil, errors.New("baseline was created with a different fingerprint key")
+	}
+	for _, entry := range file.Entries {
+		b.entries[entry.key()] = entry
+	}
+	return b, nil
+}
+
+// Add adds entry to the baseline, keeping any existing entry for the same
+// fingerprint, provider and path. It reports whether entry was new.
+func (b *Baseline) Add(entry BaselineEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := entry.key()
+	b.seen[key] = true
+	if _, ok := b.entries[key]; ok {
+		return false
+	}
+	b.entries[key] = &entry
+	return true
+}
+
+// Entries returns the baseline's entries, sorted by path, provider and
+// fingerprint.
+func (b *Baseline) Entries() []BaselineEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sorted(func(baselineKey) bool { return true })
+}
+
+// Stale returns the entries that matched no finding since the baseline was
+// loaded. After a scan of everything the baseline covers, these are secrets
+// that have been removed.
+func (b *Baseline) Stale() []BaselineEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sorted(func(key baselineKey) bool { return !b.seen[key] })
+}
+
+// Prune removes stale entries and returns how many were removed.
+func (b *Baseline) Prune() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	removed := 0
+	for key := range b.entries {
+		if !b.seen[key] {
+			delete(b.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (b *Baseline) sorted(include func(baselineKey) bool) []BaselineEntry {
+	var entries []BaselineEntry
+	for key, entry := range b.entries {
+		if include(key) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+	return entries
+}
+
+// Write writes the baseline as indented JSON with entries in a stable order,
+// so it diffs cleanly when committed to a repository.
+func (b *Baseline) Write(w io.Writer) error {
+	entries := b.Entries()
+	file := baselineFile{
+		Version:          baselineVersion,
+		FingerprintCheck: b.fingerprintCheck,
+		Entries:          make([]*BaselineEntry, len(entries)),
+	}
+	for i := range entries {
+		file.Entries[i] = &entries[i]
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// Save writes the baseline to path, replacing it atomically.
+func (b *Baseline) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".baseline-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := b.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// relative returns path relative to the baseline's root. Paths outside the
+// root, such as "-" for stdin, are returned as reported.
+func (b *Baseline) relative(path string) string {
+	if b.root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(b.root, filepath.FromSlash(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// match returns the entry acknowledging f, if any, and marks it as seen.
+func (b *Baseline) match(f *findings.Finding) *BaselineEntry {
+	path := b.relative(f.Path)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range []baselineKey{
+		{fingerprint: f.Fingerprint, provider: f.Provider, path: path},
+		{fingerprint: f.Fingerprint, provider: f.Provider},
+	} {
+		if entry, ok := b.entries[key]; ok {
+			b.seen[key] = true
+			return entry
+		}
+	}
+	return nil
+}
+
+// Name implements findings.Filter.
+func (b *Baseline) Name() string {
+	return "baseline"
+}
+
+// Filter implements findings.Filter, suppressing acknowledged findings.
+func (b *Baseline) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	kept := found[:0]
+	for _, f := range found {
+		if entry := b.match(f); entry != nil {
+			f.Decide(b.Name(), findings.OutcomeSuppress, "acknowledged by "+entry.AcknowledgedBy+": "+entry.Reason)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// BaselineMatchProcessor adds every finding it processes to a baseline, for
+// creating or extending one from a scan.
+type BaselineMatchProcessor struct {
+	baseline       *Baseline
+	acknowledgedBy string
+	reason         string
+	now            time.Time
+}
+
+// NewBaselineMatchProcessor returns a BaselineMatchProcessor adding to
+// baseline. New entries are attributed to acknowledgedBy with reason.
+func NewBaselineMatchProcessor(baseline *Baseline, acknowledgedBy, reason string) *BaselineMatchProcessor {
+	return &BaselineMatchProcessor{
+		baseline:       baseline,
+		acknowledgedBy: acknowledgedBy,
+		reason:         reason,
+		now:            time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *BaselineMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	p.baseline.Add(BaselineEntry{
+		Fingerprint:    f.Fingerprint,
+		Provider:       f.Provider,
+		Path:           p.baseline.relative(f.Path),
+		AcknowledgedBy: p.acknowledgedBy,
+		AcknowledgedAt: p.now,
+		Reason:         p.reason,
+	})
+	return nil
+}
+
+// Flush implements findings.Processor.
+func (p *BaselineMatchProcessor) Flush() error {
+	return nil
+}
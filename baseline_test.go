@@ -0,0 +1,96 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fp := findings.NewFingerprinter([]byte("baseline-test"))
+	finding := func(path, secret string) *findings.Finding {
+		return &findings.Finding{
+			Provider:    "ADAFRUIT_AIO_KEY",
+			Path:        path,
+			Secret:      []byte(secret),
+			Fingerprint: fp.Fingerprint("ADAFRUIT_AIO_KEY", []byte(secret)),
+		}
+	}
+	known := finding("docs/setup.md", "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+	removed := finding("old.txt", "aio_AFAl78NPVow9ATNDZ0AgvICgLygp")
+
+	// Create a baseline from a first scan.
+	baseline := NewBaseline(fp.Fingerprint, "")
+	processor := NewBaselineMatchProcessor(baseline, "monalisa", "sample tokens in docs")
+	require.NoError(t, processor.ProcessFinding(ctx, known))
+	require.NoError(t, processor.ProcessFinding(ctx, removed))
+	var file bytes.Buffer
+	require.NoError(t, baseline.Write(&file))
+	require.NotContains(t, file.String(), "aio_")
+
+	// A later scan only reports what is new.
+	baseline, err := ReadBaseline(bytes.NewReader(file.Bytes()), fp.Fingerprint, "")
+	require.NoError(t, err)
+	entries := baseline.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "monalisa", entries[0].AcknowledgedBy)
+	require.Equal(t, "sample tokens in docs", entries[0].Reason)
+
+	fresh := finding("docs/setup.md", "aio_HKhn497s5qA21gC1iwSn4V5qTjV0")
+	moved := finding("README.md", "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+	again := finding("docs/setup.md", "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+	kept := baseline.Filter(ctx, nil, []*findings.Finding{again, fresh, moved})
+	require.Equal(t, []*findings.Finding{fresh, moved}, kept)
+	require.Equal(t, findings.OutcomeSuppress, again.Decisions[0].Outcome)
+
+	stale := baseline.Stale()
+	require.Len(t, stale, 1)
+	require.Equal(t, "old.txt", stale[0].Path)
+	require.Equal(t, 1, baseline.Prune())
+	require.Len(t, baseline.Entries(), 1)
+
+	// An entry without a path acknowledges the secret everywhere.
+	baseline.Add(BaselineEntry{Fingerprint: moved.Fingerprint, Provider: moved.Provider})
+	require.Empty(t, baseline.Filter(ctx, nil, []*findings.Finding{moved}))
+}
+
+func TestBaselineRejectsOtherFingerprintKey(t *testing.T) {
+	t.Parallel()
+	var file bytes.Buffer
+	require.NoError(t, NewBaseline(findings.NewFingerprinter([]byte("one")).Fingerprint, "").Write(&file))
+	_, err := ReadBaseline(&file, findings.NewFingerprinter([]byte("two")).Fingerprint, "")
+	require.ErrorContains(t, err, "different fingerprint key")
+}
+
+func TestBaselinePathsAreRelativeToRoot(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fp := findings.NewFingerprinter([]byte("baseline-test"))
+	finding := func(path string) *findings.Finding {
+		secret := []byte("aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+		return &findings.Finding{Provider: "ADAFRUIT_AIO_KEY", Path: path, Secret: secret, Fingerprint: fp.Fingerprint("ADAFRUIT_AIO_KEY", secret)}
+	}
+
+	// Created with "baseline repo/".
+	baseline := NewBaseline(fp.Fingerprint, "repo")
+	require.NoError(t, NewBaselineMatchProcessor(baseline, "monalisa", "sample").ProcessFinding(ctx, finding("repo/docs/setup.md")))
+	require.Equal(t, "docs/setup.md", baseline.Entries()[0].Path)
+	var file bytes.Buffer
+	require.NoError(t, baseline.Write(&file))
+
+	// Used with "cd repo && scan .".
+	baseline, err := ReadBaseline(bytes.NewReader(file.Bytes()), fp.Fingerprint, ".")
+	require.NoError(t, err)
+	require.Empty(t, baseline.Filter(ctx, nil, []*findings.Finding{finding("docs/setup.md")}))
+	// Used with "scan repo".
+	baseline, err = ReadBaseline(bytes.NewReader(file.Bytes()), fp.Fingerprint, "repo")
+	require.NoError(t, err)
+	require.Empty(t, baseline.Filter(ctx, nil, []*findings.Finding{finding("repo/docs/setup.md")}))
+	require.Len(t, baseline.Filter(ctx, nil, []*findings.Finding{finding("-")}), 1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/processors"
+)
+
+const defaultBaselinePath = ".hypercredscan-baseline.json"
+
+// runBaseline scans paths and records every finding in a baseline file.
+// Entries already in the file keep their original acknowledgement.
+func runBaseline(args []string) int {
+	flags := flag.NewFlagSet("baseline", flag.ContinueOnError)
+	output := flags.String("output", defaultBaselinePath, "baseline file to create or update")
+	author := flags.String("author", os.Getenv("USER"), "who acknowledges the new entries")
+	reason := flags.String("reason", "", "why the new entries are acknowledged")
+	prune := flags.Bool("prune", false, "remove entries that no longer match any finding")
+	repoConfig := flags.String("config", "", "repository configuration file (default "+processors.RepositoryConfigFile+" in the scanned directory, if it exists)")
+	confidenceModel := flags.String("confidence-model", "", "YAML file overriding the default confidence model's settings")
+	var paths pathFlags
+	paths.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: hypercredscan baseline [flags] PATH...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitError
+	}
+	if *reason == "" {
+		return fail(errors.New("baseline: -reason is required so reviewers know why findings were accepted"))
+	}
+
+	scanner, err := newScanner()
+	if err != nil {
+		return fail(err)
+	}
+	defer scanner.Close()
+
+	root := scanRoot(flags.Args())
+	baseline, err := hypercredscan.LoadBaseline(*output, scanner.Fingerprint, root)
+	if errors.Is(err, fs.ErrNotExist) {
+		baseline, err = hypercredscan.NewBaseline(scanner.Fingerprint, root), nil
+	}
+	if err != nil {
+		return fail(err)
+	}
+	before := len(baseline.Entries())
+	// Filter findings as scan does, so the baseline records exactly the
+	// findings scan would report.
+	model, err := loadConfidenceModel(*confidenceModel)
+	if err != nil {
+		return fail(err)
+	}
+	filters, err := newFilters(scanner, model, root)
+	if err != nil {
+		return fail(err)
+	}
+	allowlist, err := loadAllowlist(*repoConfig, root)
+	if err != nil {
+		return fail(err)
+	}
+	if allowlist != nil {
+		filters = append(filters, allowlist)
+	}
+	opts := paths.options()
+	opts.Filter = findings.Chain(filters...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	processor := hypercredscan.NewBaselineMatchProcessor(baseline, *author, *reason)
+	if err := hypercredscan.ScanPaths(ctx, scanner, flags.Args(), opts, processor); err != nil {
+		return fail(err)
+	}
+
+	pruned := 0
+	if *prune {
+		pruned = baseline.Prune()
+	}
+	if err := baseline.Save(*output); err != nil {
+		return fail(err)
+	}
+	after := len(baseline.Entries())
+	fmt.Fprintf(os.Stderr, "%s: %d entries (%d added, %d pruned)\n", *output, after, after-before+pruned, pruned)
+	return exitOK
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/lint"
+)
+
+// runLint checks provider patterns, exiting with exitFindings if they fail
+// validation so it can gate CI.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	providers := flags.String("providers", "", "provider configuration file or directory to lint instead of the default providers")
+	format := flags.String("format", "text", "output format: text or json")
+	strict := flags.Bool("strict", false, "fail on warnings as well as errors")
+	minSpecificity := flags.Float64("min-specificity", lint.DefaultMinSpecificity, "fewest bits of specificity a pattern may have before it is reported as too broad")
+	noCompile := flags.Bool("no-compile", false, "skip compiling each pattern with Hyperscan")
+	showStats := flags.Bool("stats", false, "in text output, also list every pattern's estimated match breadth")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: hypercredscan lint [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return exitError
+	}
+
+	var cfg *config.Config
+	var declarations *config.Declarations
+	var err error
+	if *providers == "" {
+		cfg, err = config.LoadValidatedDefaultConfig()
+	} else {
+		cfg, declarations, err = config.LoadConfigPath(*providers)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	precedence, err := declarations.Precedence(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	report := lint.LintConfig(cfg, lint.Options{
+		MinSpecificity: *minSpecificity,
+		SkipCompile:    *noCompile,
+		Expected:       config.LoadDefaultExpectedOverlaps(),
+		Precedence:     precedence,
+	})
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fail(err)
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Println(issue)
+		}
+		if *showStats {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "PROVIDER\tMIN\tMAX\tBITS\tLITERAL")
+			for _, p := range report.Patterns {
+				if !p.Analyzed {
+					fmt.Fprintf(w, "%s\t-\t-\t-\t-\n", p.Provider)
+					continue
+				}
+				maxLength := fmt.Sprint(p.MaxLength)
+				if p.MaxLength < 0 {
+					maxLength = "unbounded"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%.0f\t%q\n", p.Provider, p.MinLength, maxLength, p.Specificity, p.Literal)
+			}
+			if err := w.Flush(); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d providers: %d errors, %d warnings\n",
+			len(report.Patterns), report.Count(lint.SeverityError), report.Count(lint.SeverityWarning))
+	}
+	if report.Failed(*strict) {
+		return exitFindings
+	}
+	return exitOK
+}
@@ -0,0 +1,48 @@
+// Command hypercredscan scans files for credentials using the same provider
+// configuration as the production scanning service.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+// commands maps subcommand names to their entry points. Each receives the
+// arguments following the subcommand name and returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"scan":     runScan,
+	"baseline": runBaseline,
+	"serve":    runServe,
+	"lint":     runLint,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitError)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "hypercredscan: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(exitError)
+	}
+	os.Exit(run(os.Args[2:]))
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "usage: hypercredscan <%s> [flags] [args]\n", strings.Join(names, "|"))
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/github/go-stats"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/processors"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/validation"
+)
+
+// fingerprintKeyEnv names the environment variable holding the key used to
+// fingerprint secrets. Keeping it out of flags keeps it out of process
+// listings.
+const fingerprintKeyEnv = "HYPERCREDSCAN_FINGERPRINT_KEY"
+
+var warnDefaultFingerprintKey sync.Once
+
+// fingerprintKey returns the key in fingerprintKeyEnv. Without one, scanners
+// fall back to the public findings.DefaultFingerprintKey, which lets anyone
+// holding a fingerprint confirm a guessed secret, so that is warned about once.
+func fingerprintKey() []byte {
+	key := os.Getenv(fingerprintKeyEnv)
+	if key == "" {
+		warnDefaultFingerprintKey.Do(func() {
+			fmt.Fprintf(os.Stderr, "hypercredscan: warning: %s is not set; fingerprints use the public default key and must not be shared\n", fingerprintKeyEnv)
+		})
+	}
+	return []byte(key)
+}
+
+// pathFlags are the flags controlling which files are scanned, shared by the
+// commands that walk paths.
+type pathFlags struct {
+	workers       int
+	maxSize       int64
+	noGitignore   bool
+	includeBinary bool
+	archives      bool
+}
+
+func (f *pathFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.workers, "workers", 0, "number of files scanned in parallel (default: number of CPUs)")
+	fs.Int64Var(&f.maxSize, "max-size", hypercredscan.DefaultMaxFileSize, "skip files larger than this many bytes; negative disables the limit")
+	fs.BoolVar(&f.noGitignore, "no-gitignore", false, "scan files excluded by .gitignore")
+	fs.BoolVar(&f.includeBinary, "include-binary", false, "scan files that look binary")
+	fs.BoolVar(&f.archives, "archives", false, "descend into zip and tar archives")
+}
+
+func (f *pathFlags) options() hypercredscan.PathScanOptions {
+	return hypercredscan.PathScanOptions{
+		Workers:         f.workers,
+		MaxFileSize:     f.maxSize,
+		IgnoreGitignore: f.noGitignore,
+		IncludeBinary:   f.includeBinary,
+		ScanArchives:    f.archives,
+		Reporter:        stderrReporter{},
+	}
+}
+
+// scanRoot returns the repository root of the paths being scanned: the
+// directory named when it is the only path, the working directory otherwise.
+func scanRoot(paths []string) string {
+	if len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.IsDir() {
+			return filepath.Clean(paths[0])
+		}
+	}
+	return "."
+}
+
+// loadAllowlist builds the allowlist from the repository configuration at
+// path, or at RepositoryConfigFile in root if path is empty. Allowlist paths
+// are relative to root. A missing file is only an error if it was named
+// explicitly.
+func loadAllowlist(path, root string) (*processors.Allowlist, error) {
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(root, processors.RepositoryConfigFile)
+	}
+	cfg, err := processors.LoadRepositoryConfig(path)
+	if errors.Is(err, iofs.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return processors.NewAllowlist(cfg.Allowlist, root, stats.NullStatter)
+}
+
+// newScanner builds a scanner over the default provider configuration.
+func newScanner() (*hypercredscan.Scanner, error) {
+	cfg, err := config.LoadValidatedDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	return hypercredscan.NewScanner(cfg, hypercredscan.WithFingerprintKey(fingerprintKey()))
+}
+
+// loadConfidenceModel returns the default confidence model, with the settings
+// in path applied over it if path is set.
+func loadConfidenceModel(path string) (*config.ConfidenceModel, error) {
+	if path == "" {
+		return config.LoadDefaultConfidenceModel()
+	}
+	return config.LoadConfidenceModel(path)
+}
+
+// newFilters returns the filters applied to every finding of scanner, ahead
+// of any allowlist or baseline. Paths are classified relative to root, as
+// returned by scanRoot, or as reported if it is empty.
+func newFilters(scanner *hypercredscan.Scanner, model *config.ConfidenceModel, root string) ([]findings.Filter, error) {
+	examples, err := config.LoadDefaultKnownExamples()
+	if err != nil {
+		return nil, err
+	}
+	return []findings.Filter{
+		processors.NewPlaceholderFilter(examples),
+		processors.NewJWTFilter(),
+		processors.NewPrivateKeyFilter(),
+		processors.NewAWSKeyIDFilter(),
+		processors.NewConfidenceScorer(model, scanner.Config().HyperscanProviders(), root),
+	}, nil
+}
+
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: "+strings.Join(hypercredscan.OutputFormats(), ", "))
+	output := fs.String("output", "", "write findings to this file instead of stdout")
+	includeSecrets := fs.Bool("include-secrets", false, "write raw secret values in jsonl and csv output; by default only the keyed fingerprint identifies a secret")
+	baselinePath := fs.String("baseline", "", "suppress findings acknowledged in this baseline file")
+	reportStale := fs.Bool("report-stale", false, "list baseline entries that matched nothing on stderr")
+	repoConfig := fs.String("config", "", "repository configuration file (default "+processors.RepositoryConfigFile+" in the scanned directory, if it exists)")
+	confidenceModel := fs.String("confidence-model", "", "YAML file overriding the default confidence model's settings")
+	minConfidence := fs.Float64("min-confidence", 0, "report only findings with at least this confidence score, such as the confidence model's suggested threshold; 0 reports everything")
+	validate := fs.Bool("validate", false, "check findings with their vendors' APIs; this sends detected secrets to the vendors that issued them")
+	auditAllowlist := fs.Bool("audit-allowlist", false, "print how many findings each allowlist rule suppressed on stderr")
+	var paths pathFlags
+	paths.register(fs)
+	echo := fs.Bool("echo", false, "when scanning stdin, copy it to stdout with detected tokens masked; findings go to stderr unless -output is set")
+	overlap := fs.Int("overlap", hypercredscan.DefaultStreamOverlap, "when scanning stdin, bytes carried between windows; must exceed the longest token")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hypercredscan scan [flags] PATH...")
+		fmt.Fprintln(fs.Output(), "       hypercredscan scan [flags] -    (scan stdin as it is written)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	stdin := fs.NArg() == 1 && fs.Arg(0) == "-"
+	var w io.Writer = os.Stdout
+	if stdin && *echo {
+		w = os.Stderr
+	}
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	scanner, err := newScanner()
+	if err != nil {
+		return fail(err)
+	}
+	defer scanner.Close()
+	secrets := hypercredscan.SecretsOmitted
+	if *includeSecrets {
+		secrets = hypercredscan.SecretsRaw
+	}
+	processor, err := hypercredscan.NewFormatMatchProcessor(*format, w, hypercredscan.FormatOptions{Config: scanner.Config(), Secrets: secrets})
+	if err != nil {
+		return fail(err)
+	}
+	model, err := loadConfidenceModel(*confidenceModel)
+	if err != nil {
+		return fail(err)
+	}
+	root := scanRoot(fs.Args())
+	filters, err := newFilters(scanner, model, root)
+	if err != nil {
+		return fail(err)
+	}
+	allowlist, err := loadAllowlist(*repoConfig, root)
+	if err != nil {
+		return fail(err)
+	}
+	if allowlist != nil {
+		filters = append(filters, allowlist)
+	}
+	var baseline *hypercredscan.Baseline
+	if *baselinePath != "" {
+		if baseline, err = hypercredscan.LoadBaseline(*baselinePath, scanner.Fingerprint, root); err != nil {
+			return fail(err)
+		}
+		filters = append(filters, baseline)
+	}
+	filter := findings.Chain(filters...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Findings below the threshold are neither validated, written nor counted
+	// towards the exit code.
+	counter := &countingProcessor{Processor: processor}
+	var sink findings.Processor = counter
+	if *validate {
+		registry, err := validation.NewHTTPRegistry(validation.DefaultHTTPChecks(), validation.DefaultHTTPOptions())
+		if err != nil {
+			return fail(err)
+		}
+		sink = validation.NewProcessor(ctx, sink, registry, validation.ProcessorOptions{})
+	}
+	if *minConfidence > 0 {
+		sink = hypercredscan.NewConfidenceThresholdMatchProcessor(sink, *minConfidence)
+	}
+	if stdin {
+		streamOpts := hypercredscan.StreamOptions{Path: "-", Overlap: *overlap, Filter: filter}
+		if *echo {
+			streamOpts.Echo = os.Stdout
+		}
+		stream := hypercredscan.NewStreamScanner(ctx, scanner, sink, streamOpts)
+		if _, err := stream.ReadFrom(os.Stdin); err != nil {
+			return fail(err)
+		}
+		if err := stream.Close(); err != nil {
+			return fail(err)
+		}
+	} else {
+		opts := paths.options()
+		opts.Filter = filter
+		if err := hypercredscan.ScanPaths(ctx, scanner, fs.Args(), opts, sink); err != nil {
+			return fail(err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		return fail(err)
+	}
+	if allowlist != nil && *auditAllowlist {
+		counts := allowlist.Counts()
+		rules := make([]string, 0, len(counts))
+		for rule := range counts {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			fmt.Fprintf(os.Stderr, "allowlist rule %s suppressed %d findings\n", rule, counts[rule])
+		}
+	}
+	if baseline != nil && *reportStale {
+		for _, entry := range baseline.Stale() {
+			fmt.Fprintf(os.Stderr, "stale baseline entry: %s %s %s (acknowledged by %s)\n", entry.Path, entry.Provider, entry.Fingerprint, entry.AcknowledgedBy)
+		}
+	}
+	if counter.count.Load() > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// countingProcessor forwards findings while counting them, so the exit code
+// can reflect whether anything was found.
+type countingProcessor struct {
+	findings.Processor
+	count atomic.Int64
+}
+
+func (p *countingProcessor) ProcessFinding(ctx context.Context, f *findings.Finding) error {
+	p.count.Add(1)
+	return p.Processor.ProcessFinding(ctx, f)
+}
+
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "hypercredscan: %v\n", err)
+	return exitError
+}
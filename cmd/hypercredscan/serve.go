@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/github/go-stats"
+	"google.golang.org/grpc"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/scanrpc"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/server"
+)
+
+// shutdownTimeout is how long in-flight requests get to finish once the
+// server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// runServe serves scan requests over HTTP until interrupted.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	maxRequestBytes := flags.Int64("max-request-bytes", server.DefaultMaxRequestBytes, "largest request body accepted")
+	timeout := flags.Duration("timeout", server.DefaultTimeout, "longest a scan request may take")
+	maxConcurrent := flags.Int("max-concurrent", 0, "scan requests served at once; more are rejected (default: number of CPUs)")
+	grpcAddr := flags.String("grpc-addr", "", "also serve the gRPC Scanner service on this address")
+	providers := flags.String("providers", "", "provider configuration file or directory to scan with instead of the default providers; reloaded when it changes or on SIGHUP")
+	reloadInterval := flags.Duration("reload-interval", hypercredscan.DefaultReloadInterval, "how often -providers is checked for changes")
+	confidenceModel := flags.String("confidence-model", "", "YAML file overriding the default confidence model's settings")
+	policyPath := flags.String("policy", "", "YAML policy selecting the providers enabled per tenant and repository")
+	policyCacheSize := flags.Int("policy-cache-size", hypercredscan.DefaultPolicyCacheSize, "provider subsets kept compiled for -policy")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: hypercredscan serve [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitError
+	}
+	model, err := loadConfidenceModel(*confidenceModel)
+	if err != nil {
+		return fail(err)
+	}
+	var policy *config.Policy
+	if *policyPath != "" {
+		if policy, err = config.LoadPolicy(*policyPath); err != nil {
+			return fail(err)
+		}
+	}
+
+	srv := server.New(server.Options{
+		MaxRequestBytes: *maxRequestBytes,
+		Timeout:         *timeout,
+		MaxConcurrent:   *maxConcurrent,
+	})
+	httpServer := &http.Server{Addr: *addr, Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	service := scanrpc.NewService(stats.NullStatter)
+	if *grpcAddr != "" {
+		grpcListener, err = net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return fail(err)
+		}
+		grpcServer = grpc.NewServer()
+		scanrpc.RegisterScannerServer(grpcServer, service)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Compile the database while already answering health checks, so
+	// orchestrators can tell a starting server from a dead one.
+	loadErr := make(chan error, 1)
+	go func() {
+		source, err := newScannerSource(ctx, *providers, *reloadInterval, model, policy)
+		if err != nil {
+			loadErr <- err
+			return
+		}
+		if policy != nil {
+			source = newPolicySource(source, policy, *policyCacheSize)
+		}
+		srv.LoadSource(source)
+		service.LoadSource(source)
+		fmt.Fprintf(os.Stderr, "hypercredscan: provider database loaded, serving on %s\n", *addr)
+	}()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	if grpcServer != nil {
+		go func() {
+			serveErr <- grpcServer.Serve(grpcListener)
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return fail(err)
+	case err := <-loadErr:
+		_ = httpServer.Close()
+		return fail(err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+	}()
+	err = httpServer.Shutdown(shutdownCtx)
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-shutdownCtx.Done():
+			// GracefulStop waits on streams indefinitely; cut them off.
+			grpcServer.Stop()
+		}
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// newScannerSource builds the scanner the server scans with: over the default
+// providers, or over those at path, reloading them every interval and on
+// SIGHUP until ctx is done. If policy is not nil, every configuration is
+// checked to have the providers it names, and a reload failing the check is
+// rejected.
+func newScannerSource(ctx context.Context, path string, interval time.Duration, model *config.ConfidenceModel, policy *config.Policy) (hypercredscan.ScannerSource, error) {
+	var validate func(*config.Config) error
+	if policy != nil {
+		validate = func(cfg *config.Config) error {
+			return policy.Validate(cfg.HyperscanProviders())
+		}
+	}
+	buildFilter := func(scanner *hypercredscan.Scanner) (findings.Filter, error) {
+		filters, err := newFilters(scanner, model, "")
+		if err != nil {
+			return nil, err
+		}
+		return findings.Chain(filters...), nil
+	}
+	if path == "" {
+		scanner, err := newScanner()
+		if err != nil {
+			return nil, err
+		}
+		if validate != nil {
+			if err := validate(scanner.Config()); err != nil {
+				return nil, err
+			}
+		}
+		filter, err := buildFilter(scanner)
+		if err != nil {
+			return nil, err
+		}
+		return hypercredscan.StaticSource(scanner, filter), nil
+	}
+
+	manager, err := hypercredscan.NewConfigManager(path, stderrReporter{}, hypercredscan.ConfigManagerOptions{
+		Interval:       interval,
+		ScannerOptions: []hypercredscan.ScannerOption{hypercredscan.WithFingerprintKey(fingerprintKey())},
+		Validate:       validate,
+		Filter:         buildFilter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go manager.Run(ctx)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				if manager.Reload(ctx) == nil {
+					fmt.Fprintf(os.Stderr, "hypercredscan: reloaded providers from %s\n", path)
+				}
+			}
+		}
+	}()
+	return manager, nil
+}
+
+// newPolicySource restricts the scanners of source to the providers policy
+// enables for each identity. newScannerSource checks that every
+// configuration source scans with is one the policy applies to.
+func newPolicySource(source hypercredscan.ScannerSource, policy *config.Policy, cacheSize int) hypercredscan.ScannerSource {
+	return hypercredscan.NewPolicySource(source, policy, hypercredscan.PolicySourceOptions{
+		CacheSize:      cacheSize,
+		ScannerOptions: []hypercredscan.ScannerOption{hypercredscan.WithFingerprintKey(fingerprintKey())},
+		Reporter:       stderrReporter{},
+	})
+}
+
+// stderrReporter reports failed reloads, archives that cannot be expanded and
+// other errors that do not stop a command on stderr.
+type stderrReporter struct{}
+
+func (stderrReporter) Report(_ context.Context, err error, _ map[string]interface{}) {
+	fmt.Fprintf(os.Stderr, "hypercredscan: %v\n", err)
+}
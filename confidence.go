@@ -0,0 +1,33 @@
+package hypercredscan
+
+import (
+	"context"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// ConfidenceThresholdMatchProcessor passes findings whose confidence score is
+// at least a minimum on to another processor and drops the rest.
+type ConfidenceThresholdMatchProcessor struct {
+	next findings.Processor
+	min  float64
+}
+
+// NewConfidenceThresholdMatchProcessor returns a processor passing findings
+// scored at least min to next.
+func NewConfidenceThresholdMatchProcessor(next findings.Processor, min float64) *ConfidenceThresholdMatchProcessor {
+	return &ConfidenceThresholdMatchProcessor{next: next, min: min}
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *ConfidenceThresholdMatchProcessor) ProcessFinding(ctx context.Context, f *findings.Finding) error {
+	if f.Confidence < p.min {
+		return nil
+	}
+	return p.next.ProcessFinding(ctx, f)
+}
+
+// Flush implements findings.Processor.
+func (p *ConfidenceThresholdMatchProcessor) Flush() error {
+	return p.next.Flush()
+}
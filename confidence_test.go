@@ -0,0 +1,22 @@
+package hypercredscan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestConfidenceThresholdMatchProcessor(t *testing.T) {
+	t.Parallel()
+	next := &collectingProcessor{}
+	processor := NewConfidenceThresholdMatchProcessor(next, 0.5)
+	for _, confidence := range []float64{0.2, 0.5, 0.9} {
+		require.NoError(t, processor.ProcessFinding(context.Background(), &findings.Finding{Provider: "GITHUB_PAT", Confidence: confidence}))
+	}
+	require.NoError(t, processor.Flush())
+	require.Len(t, next.findings, 2)
+	require.Equal(t, 0.5, next.findings[0].Confidence)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed confidence.yml
+var confidenceYAML []byte
+
+// ConfidenceWeights are the weights of the signals making up a finding's
+// confidence score. confidence.yml describes each signal.
+type ConfidenceWeights struct {
+	Specificity float64 `yaml:"specificity"`
+	Checksum    float64 `yaml:"checksum"`
+	Entropy     float64 `yaml:"entropy"`
+	Keyword     float64 `yaml:"keyword"`
+	Path        float64 `yaml:"path"`
+	VendorName  float64 `yaml:"vendor_name"`
+	Placeholder float64 `yaml:"placeholder"`
+}
+
+// ConfidenceModel configures how findings are scored.
+type ConfidenceModel struct {
+	Base float64 `yaml:"base"`
+	// Threshold is the minimum score suggested to consumers that opt in to
+	// thresholding on confidence. Nothing drops findings below it by default.
+	Threshold       float64           `yaml:"threshold"`
+	MinPrefixLength int               `yaml:"min_prefix_length"`
+	KeywordWindow   int               `yaml:"keyword_window"`
+	Keywords        []string          `yaml:"keywords"`
+	Weights         ConfidenceWeights `yaml:"weights"`
+}
+
+// LoadDefaultConfidenceModel returns the confidence model shipped with the
+// scanner.
+func LoadDefaultConfidenceModel() (*ConfidenceModel, error) {
+	return ParseConfidenceModel(confidenceYAML)
+}
+
+// LoadConfidenceModel reads a confidence model from path. Settings missing
+// from the file keep their default values, so a file may override only the
+// weights it cares about.
+func LoadConfidenceModel(path string) (*ConfidenceModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	model, err := LoadDefaultConfidenceModel()
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := model.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return model, nil
+}
+
+// ParseConfidenceModel parses a complete confidence model in the format of
+// the shipped confidence.yml.
+func ParseConfidenceModel(data []byte) (*ConfidenceModel, error) {
+	var model ConfidenceModel
+	if err := yaml.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("parsing confidence model: %w", err)
+	}
+	if err := model.validate(); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (m *ConfidenceModel) validate() error {
+	if m.Base < 0 || m.Base > 1 {
+		return fmt.Errorf("confidence base %v is outside [0, 1]", m.Base)
+	}
+	if m.Threshold < 0 || m.Threshold > 1 {
+		return fmt.Errorf("confidence threshold %v is outside [0, 1]", m.Threshold)
+	}
+	if m.KeywordWindow < 0 {
+		return fmt.Errorf("keyword window %d is negative", m.KeywordWindow)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed known_examples.yml
+var knownExamplesYAML []byte
+
+// KnownExample is a credential-like value published as an example.
+type KnownExample struct {
+	Value string `yaml:"value"`
+	// Provider, when set, limits the example to findings of that provider.
+	Provider string `yaml:"provider"`
+	Source   string `yaml:"source"`
+}
+
+// KnownExamples describes the values and patterns that identify example and
+// placeholder credentials.
+type KnownExamples struct {
+	Examples         []KnownExample `yaml:"examples"`
+	Words            []string       `yaml:"words"`
+	MinRepeatedRun   int            `yaml:"min_repeated_run"`
+	MinSequentialRun int            `yaml:"min_sequential_run"`
+}
+
+// LoadDefaultKnownExamples returns the known examples shipped with the
+// scanner.
+func LoadDefaultKnownExamples() (*KnownExamples, error) {
+	return ParseKnownExamples(knownExamplesYAML)
+}
+
+// ParseKnownExamples parses known examples in the format of the shipped
+// known_examples.yml.
+func ParseKnownExamples(data []byte) (*KnownExamples, error) {
+	var examples KnownExamples
+	if err := yaml.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("parsing known examples: %w", err)
+	}
+	for i, example := range examples.Examples {
+		if example.Value == "" {
+			return nil, fmt.Errorf("known example %d has no value", i)
+		}
+	}
+	return &examples, nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed overlaps.yml
+var overlapsYAML []byte
+
+// ExpectedOverlap declares that two providers are known to match the same
+// tokens.
+type ExpectedOverlap struct {
+	Providers []string `yaml:"providers"`
+	Reason    string   `yaml:"reason"`
+}
+
+// ExpectedOverlaps holds declared overlaps, looked up by either provider.
+type ExpectedOverlaps struct {
+	byPair map[[2]string]*ExpectedOverlap
+}
+
+// expectedOverlaps are the overlaps declared in the shipped overlaps.yml.
+var expectedOverlaps = mustParseExpectedOverlaps(overlapsYAML)
+
+// LoadDefaultExpectedOverlaps returns the overlaps shipped with the scanner.
+func LoadDefaultExpectedOverlaps() *ExpectedOverlaps {
+	return expectedOverlaps
+}
+
+// ParseExpectedOverlaps parses overlap declarations in the format of the
+// shipped overlaps.yml.
+func ParseExpectedOverlaps(data []byte) (*ExpectedOverlaps, error) {
+	var file struct {
+		Overlaps []*ExpectedOverlap `yaml:"overlaps"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing expected overlaps: %w", err)
+	}
+	overlaps := &ExpectedOverlaps{byPair: make(map[[2]string]*ExpectedOverlap, len(file.Overlaps))}
+	for i, overlap := range file.Overlaps {
+		if len(overlap.Providers) != 2 || overlap.Providers[0] == "" || overlap.Providers[1] == "" || overlap.Providers[0] == overlap.Providers[1] {
+			return nil, fmt.Errorf("overlap %d must name two different providers", i)
+		}
+		if overlap.Reason == "" {
+			return nil, fmt.Errorf("overlap of %s and %s has no reason", overlap.Providers[0], overlap.Providers[1])
+		}
+		pair := overlapPair(overlap.Providers[0], overlap.Providers[1])
+		if _, ok := overlaps.byPair[pair]; ok {
+			return nil, fmt.Errorf("overlap of %s and %s is declared more than once", pair[0], pair[1])
+		}
+		overlaps.byPair[pair] = overlap
+	}
+	return overlaps, nil
+}
+
+func mustParseExpectedOverlaps(data []byte) *ExpectedOverlaps {
+	o, err := ParseExpectedOverlaps(data)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+func overlapPair(a, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Lookup returns the declaration of the overlap of providers a and b, in
+// either order, or nil if none was declared.
+func (o *ExpectedOverlaps) Lookup(a, b string) *ExpectedOverlap {
+	if o == nil {
+		return nil
+	}
+	return o.byPair[overlapPair(a, b)]
+}
+
+// All returns every declared overlap, sorted by the providers' names.
+func (o *ExpectedOverlaps) All() []*ExpectedOverlap {
+	if o == nil {
+		return nil
+	}
+	pairs := make([][2]string, 0, len(o.byPair))
+	for pair := range o.byPair {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	all := make([]*ExpectedOverlap, len(pairs))
+	for i, pair := range pairs {
+		all[i] = o.byPair[pair]
+	}
+	return all
+}
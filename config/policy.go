@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderSelection enables and disables providers.
+type ProviderSelection struct {
+	Enable  []string `yaml:"enable"`
+	Disable []string `yaml:"disable"`
+}
+
+// TenantPolicy selects the providers scanned for a tenant, and for
+// individual repositories of the tenant, keyed by repository name.
+type TenantPolicy struct {
+	ProviderSelection `yaml:",inline"`
+	Repositories      map[string]*ProviderSelection `yaml:"repositories"`
+}
+
+// Policy selects the providers enabled for a tenant and repository. Every
+// configured provider is enabled unless it is opt-in; the tenant's selection
+// is applied next, then the repository's, so a repository can override its
+// tenant.
+//
+//	opt_in: [ACME_INTERNAL_TOKEN]
+//	tenants:
+//	  acme:
+//	    enable: [ACME_INTERNAL_TOKEN]
+//	    repositories:
+//	      acme/fixtures:
+//	        disable: [GENERIC_JWT]
+type Policy struct {
+	// OptIn providers are disabled unless a tenant or repository enables
+	// them.
+	OptIn   []string                 `yaml:"opt_in"`
+	Tenants map[string]*TenantPolicy `yaml:"tenants"`
+}
+
+// LoadPolicy reads a policy from path.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses a policy. A provider may not be both enabled and
+// disabled by the same selection.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+	for tenant, tp := range policy.Tenants {
+		if tp == nil {
+			return nil, fmt.Errorf("tenant %s has no policy", tenant)
+		}
+		if err := tp.validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		for repository, selection := range tp.Repositories {
+			if selection == nil {
+				return nil, fmt.Errorf("tenant %s: repository %s has no policy", tenant, repository)
+			}
+			if err := selection.validate(); err != nil {
+				return nil, fmt.Errorf("tenant %s: repository %s: %w", tenant, repository, err)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+func (s *ProviderSelection) validate() error {
+	enabled := make(map[string]bool, len(s.Enable))
+	for _, name := range s.Enable {
+		enabled[name] = true
+	}
+	for _, name := range s.Disable {
+		if enabled[name] {
+			return fmt.Errorf("provider %s is both enabled and disabled", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks that every provider the policy names is one of providers,
+// catching misspelled names before they silently select nothing.
+func (p *Policy) Validate(providers []*ProviderConfig) error {
+	known := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		known[provider.Name] = true
+	}
+	unknown := make(map[string]bool)
+	check := func(names []string) {
+		for _, name := range names {
+			if !known[name] {
+				unknown[name] = true
+			}
+		}
+	}
+	check(p.OptIn)
+	for _, tp := range p.Tenants {
+		check(tp.Enable)
+		check(tp.Disable)
+		for _, selection := range tp.Repositories {
+			check(selection.Enable)
+			check(selection.Disable)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("policy names unknown providers: %s", strings.Join(names, ", "))
+}
+
+// Enabled returns the providers enabled for the repository of tenant, in the
+// order of providers. Providers the policy names that are not among providers
+// are ignored. A nil Policy enables every provider.
+func (p *Policy) Enabled(providers []*ProviderConfig, tenant, repository string) []*ProviderConfig {
+	if p == nil {
+		return providers
+	}
+	enabled := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		enabled[provider.Name] = true
+	}
+	for _, name := range p.OptIn {
+		enabled[name] = false
+	}
+	if tp := p.Tenants[tenant]; tp != nil {
+		tp.apply(enabled)
+		if selection := tp.Repositories[repository]; selection != nil {
+			selection.apply(enabled)
+		}
+	}
+	selected := make([]*ProviderConfig, 0, len(providers))
+	for _, provider := range providers {
+		if enabled[provider.Name] {
+			selected = append(selected, provider)
+		}
+	}
+	if len(selected) == len(providers) {
+		return providers
+	}
+	return selected
+}
+
+func (s *ProviderSelection) apply(enabled map[string]bool) {
+	for _, name := range s.Enable {
+		enabled[name] = true
+	}
+	for _, name := range s.Disable {
+		enabled[name] = false
+	}
+}
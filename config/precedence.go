@@ -0,0 +1,258 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed precedence.yml
+var precedenceYAML []byte
+
+// Relation is how one provider's findings rank against the overlapping
+// findings of another.
+type Relation string
+
+const (
+	// RelationNone means no precedence is declared between the providers.
+	RelationNone Relation = ""
+	// RelationSupersedes means the provider's findings suppress the other's.
+	RelationSupersedes Relation = "supersedes"
+	// RelationSupersededBy means the other provider's findings suppress the
+	// provider's.
+	RelationSupersededBy Relation = "is-superseded-by"
+	// RelationCoexists means the findings of both providers are kept.
+	RelationCoexists Relation = "coexists"
+)
+
+// PrecedenceRule declares how a provider's findings rank against the
+// overlapping findings of other providers.
+type PrecedenceRule struct {
+	Provider     string   `yaml:"provider"`
+	Supersedes   []string `yaml:"supersedes"`
+	SupersededBy []string `yaml:"is_superseded_by"`
+	Coexists     []string `yaml:"coexists"`
+}
+
+// Precedence is a validated set of precedence rules, closed under
+// transitivity.
+type Precedence struct {
+	// outranks[a][b] is set when a supersedes b, directly or transitively.
+	outranks map[string]map[string]bool
+	// outrankedBy counts the providers superseding each provider.
+	outrankedBy map[string]int
+	coexists    map[[2]string]bool
+}
+
+// precedenceRules are the rules shipped with the scanner, keyed by provider
+// name.
+var precedenceRules = mustParsePrecedenceRules(precedenceYAML)
+
+// PrecedenceRule returns the precedence shipped for the provider, or nil if
+// it has none.
+func (p *ProviderConfig) PrecedenceRule() *PrecedenceRule {
+	return precedenceRules[p.Name]
+}
+
+// PrecedenceRule returns the precedence declared for the provider, falling
+// back to the shipped precedence, or nil if it has none.
+func (d *Declarations) PrecedenceRule(provider *ProviderConfig) *PrecedenceRule {
+	if d != nil {
+		if rule, ok := d.precedence[provider.Name]; ok {
+			return rule
+		}
+	}
+	return provider.PrecedenceRule()
+}
+
+// Precedence returns the precedence shipped for the configuration's
+// providers. It fails if the rules form a cycle or declare providers to
+// coexist that also supersede one another.
+func (c *Config) Precedence() (*Precedence, error) {
+	return (*Declarations)(nil).Precedence(c)
+}
+
+// Precedence returns the precedence of cfg's providers, taking their rules
+// from the declarations before the shipped rules. It fails like
+// Config.Precedence, and if a rule names a provider that is neither in cfg
+// nor shipped.
+func (d *Declarations) Precedence(cfg *Config) (*Precedence, error) {
+	var rules []*PrecedenceRule
+	for _, provider := range cfg.HyperscanProviders() {
+		if rule := d.PrecedenceRule(provider); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	known := append(append([]*ProviderConfig(nil), cfg.HyperscanProviders()...), GetDefaultConfig()...)
+	return NewPrecedence(rules, known)
+}
+
+// ParsePrecedenceRules parses precedence rules in the format of the shipped
+// precedence.yml.
+func ParsePrecedenceRules(data []byte) (map[string]*PrecedenceRule, error) {
+	var file struct {
+		Precedence []*PrecedenceRule `yaml:"precedence"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing precedence: %w", err)
+	}
+	byProvider := make(map[string]*PrecedenceRule, len(file.Precedence))
+	for i, rule := range file.Precedence {
+		if rule.Provider == "" {
+			return nil, fmt.Errorf("precedence rule %d has no provider", i)
+		}
+		if _, ok := byProvider[rule.Provider]; ok {
+			return nil, fmt.Errorf("precedence of %s is declared more than once", rule.Provider)
+		}
+		byProvider[rule.Provider] = rule
+	}
+	rules := make([]*PrecedenceRule, 0, len(byProvider))
+	for _, rule := range byProvider {
+		rules = append(rules, rule)
+	}
+	if _, err := closePrecedence(rules); err != nil {
+		return nil, err
+	}
+	return byProvider, nil
+}
+
+func mustParsePrecedenceRules(data []byte) map[string]*PrecedenceRule {
+	rules, err := ParsePrecedenceRules(data)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// NewPrecedence validates rules and closes them under transitivity. Every
+// provider the rules name must be one of providers, so a misspelled name is
+// an error rather than a rule that never applies.
+func NewPrecedence(rules []*PrecedenceRule, providers []*ProviderConfig) (*Precedence, error) {
+	known := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		known[provider.Name] = true
+	}
+	for _, rule := range rules {
+		names := append([]string{rule.Provider}, rule.Supersedes...)
+		names = append(append(names, rule.SupersededBy...), rule.Coexists...)
+		for _, name := range names {
+			if !known[name] {
+				return nil, fmt.Errorf("precedence of %s refers to unknown provider %s", rule.Provider, name)
+			}
+		}
+	}
+	return closePrecedence(rules)
+}
+
+// closePrecedence is NewPrecedence without checking the providers named.
+func closePrecedence(rules []*PrecedenceRule) (*Precedence, error) {
+	supersedes := make(map[string][]string)
+	coexists := make(map[[2]string]bool)
+	add := func(winner, loser string) error {
+		if winner == loser {
+			return fmt.Errorf("precedence of %s refers to itself", winner)
+		}
+		supersedes[winner] = append(supersedes[winner], loser)
+		return nil
+	}
+	for _, rule := range rules {
+		for _, other := range rule.Supersedes {
+			if err := add(rule.Provider, other); err != nil {
+				return nil, err
+			}
+		}
+		for _, other := range rule.SupersededBy {
+			if err := add(other, rule.Provider); err != nil {
+				return nil, err
+			}
+		}
+		for _, other := range rule.Coexists {
+			if other == rule.Provider {
+				return nil, fmt.Errorf("precedence of %s refers to itself", other)
+			}
+			coexists[overlapPair(rule.Provider, other)] = true
+		}
+	}
+
+	p := &Precedence{
+		outranks:    make(map[string]map[string]bool),
+		outrankedBy: make(map[string]int),
+		coexists:    coexists,
+	}
+	// Visit providers in a fixed order so the reported cycle is stable.
+	winners := make([]string, 0, len(supersedes))
+	for winner, losers := range supersedes {
+		sort.Strings(losers)
+		winners = append(winners, winner)
+	}
+	sort.Strings(winners)
+	for _, winner := range winners {
+		losers := make(map[string]bool)
+		if cycle := reachable(supersedes, winner, losers, []string{winner}); cycle != nil {
+			return nil, fmt.Errorf("precedence cycle: %s", strings.Join(cycle, " supersedes "))
+		}
+		p.outranks[winner] = losers
+		for loser := range losers {
+			p.outrankedBy[loser]++
+		}
+	}
+	for pair := range coexists {
+		if p.outranks[pair[0]][pair[1]] || p.outranks[pair[1]][pair[0]] {
+			return nil, fmt.Errorf("%s and %s are declared to coexist but one supersedes the other", pair[0], pair[1])
+		}
+	}
+	return p, nil
+}
+
+// reachable adds to seen every provider superseded, directly or
+// transitively, by the last provider of path. It returns the providers of a
+// cycle if it finds one.
+func reachable(supersedes map[string][]string, root string, seen map[string]bool, path []string) []string {
+	for _, loser := range supersedes[path[len(path)-1]] {
+		if loser == root {
+			return append(path, root)
+		}
+		if seen[loser] {
+			continue
+		}
+		seen[loser] = true
+		if cycle := reachable(supersedes, root, seen, append(path, loser)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Relation returns how provider a's findings rank against overlapping
+// findings of provider b. A nil Precedence declares no relations.
+func (p *Precedence) Relation(a, b string) Relation {
+	switch {
+	case p == nil:
+		return RelationNone
+	case p.outranks[a][b]:
+		return RelationSupersedes
+	case p.outranks[b][a]:
+		return RelationSupersededBy
+	case p.coexists[overlapPair(a, b)]:
+		return RelationCoexists
+	}
+	return RelationNone
+}
+
+// Rank returns the number of providers superseding provider, directly or
+// transitively, so a provider's rank is always higher than the ranks of the
+// providers superseding it.
+func (p *Precedence) Rank(provider string) int {
+	if p == nil {
+		return 0
+	}
+	return p.outrankedBy[provider]
+}
+
+// Empty reports whether no provider supersedes another.
+func (p *Precedence) Empty() bool {
+	return p == nil || len(p.outranks) == 0
+}
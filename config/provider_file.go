@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderFile is the on-disk format of a provider configuration, as loaded
+// by LoadConfigPath.
+type ProviderFile struct {
+	// Defaults starts the provider set from GetDefaultConfig. Providers
+	// listed in the file replace default providers of the same name.
+	Defaults  bool                `yaml:"defaults"`
+	Providers []ProviderFileEntry `yaml:"providers"`
+}
+
+// ProviderFileEntry is a single provider in a ProviderFile. Metadata is
+// required unless the provider's metadata is shipped with the scanner, in
+// which case it overrides the shipped metadata. Precedence and Suppression
+// likewise override any shipped precedence rule or suppressor declaration of
+// the provider; their provider is implied.
+type ProviderFileEntry struct {
+	Name        string            `yaml:"name"`
+	Pattern     string            `yaml:"pattern"`
+	Metadata    *ProviderMetadata `yaml:"metadata"`
+	Precedence  *PrecedenceRule   `yaml:"precedence"`
+	Suppression *Suppression      `yaml:"suppression"`
+}
+
+// Declarations are the metadata, precedence rules and suppressor declarations
+// declared by provider files, keyed by provider name. They override those
+// shipped with the scanner for the configuration they were loaded with. A nil
+// Declarations declares nothing, leaving the shipped ones.
+type Declarations struct {
+	metadata    map[string]*ProviderMetadata
+	precedence  map[string]*PrecedenceRule
+	suppression map[string]*Suppression
+}
+
+// ParseProviderFile parses a provider configuration file.
+func ParseProviderFile(data []byte) (*ProviderFile, error) {
+	var file ProviderFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing provider file: %w", err)
+	}
+	seen := make(map[string]struct{}, len(file.Providers))
+	for i, entry := range file.Providers {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("provider %d has no name", i)
+		}
+		if entry.Pattern == "" {
+			return nil, fmt.Errorf("provider %s has no pattern", entry.Name)
+		}
+		if _, ok := seen[entry.Name]; ok {
+			return nil, fmt.Errorf("provider %s is declared more than once", entry.Name)
+		}
+		if entry.Metadata != nil {
+			if err := entry.Metadata.validate(); err != nil {
+				return nil, fmt.Errorf("provider %s: %w", entry.Name, err)
+			}
+		}
+		if entry.Precedence != nil {
+			if entry.Precedence.Provider != "" && entry.Precedence.Provider != entry.Name {
+				return nil, fmt.Errorf("provider %s declares the precedence of %s", entry.Name, entry.Precedence.Provider)
+			}
+			entry.Precedence.Provider = entry.Name
+		}
+		if entry.Suppression != nil {
+			if entry.Suppression.Provider != "" && entry.Suppression.Provider != entry.Name {
+				return nil, fmt.Errorf("provider %s declares the suppression of %s", entry.Name, entry.Suppression.Provider)
+			}
+			entry.Suppression.Provider = entry.Name
+			if err := entry.Suppression.validate(); err != nil {
+				return nil, err
+			}
+		}
+		seen[entry.Name] = struct{}{}
+	}
+	return &file, nil
+}
+
+// ConfigFiles returns the files LoadConfigPath reads for path: path itself if
+// it is a file, or the .yml and .yaml files directly inside it, sorted by
+// name, if it is a directory.
+func ConfigFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.Type().IsRegular() && (ext == ".yml" || ext == ".yaml") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadConfigPath loads the provider configuration in the file or directory at
+// path. The providers of a directory's files are merged in file name order; a
+// provider declared in two files is an error, as is a directory holding no
+// configuration files. The default providers are included if any file sets
+// defaults.
+//
+// The metadata, precedence and suppression declared in the files are
+// returned as Declarations, to be used with the configuration; nothing is changed for
+// configurations loaded before. Precedence rules forming a cycle, or
+// otherwise contradicting each other, are an error.
+func LoadConfigPath(path string) (*Config, *Declarations, error) {
+	files, err := ConfigFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no provider files in %s", path)
+	}
+	defaults := false
+	var entries []ProviderFileEntry
+	declaredIn := make(map[string]string)
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := ParseProviderFile(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, entry := range file.Providers {
+			if other, ok := declaredIn[entry.Name]; ok {
+				return nil, nil, fmt.Errorf("%s: provider %s is already declared in %s", name, entry.Name, other)
+			}
+			declaredIn[entry.Name] = name
+		}
+		defaults = defaults || file.Defaults
+		entries = append(entries, file.Providers...)
+	}
+
+	var providers []*ProviderConfig
+	if defaults {
+		for _, provider := range GetDefaultConfig() {
+			if _, ok := declaredIn[provider.Name]; !ok {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	declarations := &Declarations{
+		metadata:    make(map[string]*ProviderMetadata),
+		precedence:  make(map[string]*PrecedenceRule),
+		suppression: make(map[string]*Suppression),
+	}
+	for _, entry := range entries {
+		providers = append(providers, &ProviderConfig{Name: entry.Name, Pattern: entry.Pattern})
+		if entry.Metadata != nil {
+			declarations.metadata[entry.Name] = entry.Metadata
+		}
+		if entry.Precedence != nil {
+			declarations.precedence[entry.Name] = entry.Precedence
+		}
+		if entry.Suppression != nil {
+			declarations.suppression[entry.Name] = entry.Suppression
+		}
+	}
+	cfg, err := LoadCustomConfig(providers)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := declarations.Precedence(cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := declarations.ValidateMetadata(cfg); err != nil {
+		return nil, nil, err
+	}
+	return cfg, declarations, nil
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed provider_metadata.yml
+var providerMetadataYAML []byte
+
+// Severity is how much damage a leaked credential of a provider can do.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	// SeverityInfo is for providers that recognize context, such as vendor
+	// names or lockfile content, rather than secrets.
+	SeverityInfo Severity = "info"
+)
+
+// severities lists the severities from least to most severe.
+var severities = []Severity{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Valid reports whether s is one of the defined severities.
+func (s Severity) Valid() bool {
+	return s.Rank() >= 0
+}
+
+// Rank orders severities from 0 for info to 4 for critical. It is -1 for
+// unknown severities.
+func (s Severity) Rank() int {
+	for i, severity := range severities {
+		if severity == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lower returns the severity one step below s, stopping at info.
+func (s Severity) Lower() Severity {
+	if rank := s.Rank(); rank > 0 {
+		return severities[rank-1]
+	}
+	return SeverityInfo
+}
+
+// Environment is the kind of account a provider's credentials belong to.
+type Environment string
+
+const (
+	EnvironmentLive    Environment = "live"
+	EnvironmentTest    Environment = "test"
+	EnvironmentSandbox Environment = "sandbox"
+)
+
+// ProviderMetadata describes a provider for the people and systems acting on
+// its findings.
+type ProviderMetadata struct {
+	Vendor string `yaml:"vendor" json:"vendor"`
+	// Name is the human readable name of the credential type.
+	Name        string      `yaml:"name" json:"name"`
+	Severity    Severity    `yaml:"severity" json:"severity"`
+	Environment Environment `yaml:"environment" json:"environment"`
+	DocsURL     string      `yaml:"docs_url" json:"docs_url,omitempty"`
+	// Revocation tells the owner of a leaked credential how to revoke it.
+	Revocation string `yaml:"revocation" json:"revocation,omitempty"`
+}
+
+func (m *ProviderMetadata) validate() error {
+	var missing []string
+	if m.Vendor == "" {
+		missing = append(missing, "vendor")
+	}
+	if m.Name == "" {
+		missing = append(missing, "name")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, " and "))
+	}
+	if !m.Severity.Valid() {
+		return fmt.Errorf("unknown severity %q", m.Severity)
+	}
+	switch m.Environment {
+	case EnvironmentLive, EnvironmentTest, EnvironmentSandbox:
+	default:
+		return fmt.Errorf("unknown environment %q", m.Environment)
+	}
+	return nil
+}
+
+// providerMetadata is the metadata shipped with the scanner, keyed by
+// provider name.
+var providerMetadata = mustParseProviderMetadata(providerMetadataYAML)
+
+// Metadata returns the metadata shipped for the provider, or nil if it has
+// none.
+func (p *ProviderConfig) Metadata() *ProviderMetadata {
+	return providerMetadata[p.Name]
+}
+
+// Metadata returns the metadata declared for the provider, falling back to
+// the shipped metadata, or nil if it has none.
+func (d *Declarations) Metadata(provider *ProviderConfig) *ProviderMetadata {
+	if d != nil {
+		if metadata, ok := d.metadata[provider.Name]; ok {
+			return metadata
+		}
+	}
+	return provider.Metadata()
+}
+
+// ValidateMetadata checks that every provider in the configuration has
+// complete shipped metadata.
+func (c *Config) ValidateMetadata() error {
+	return (*Declarations)(nil).ValidateMetadata(c)
+}
+
+// ValidateMetadata checks that every provider in cfg has complete metadata,
+// declared or shipped.
+func (d *Declarations) ValidateMetadata(cfg *Config) error {
+	var problems []string
+	for _, provider := range cfg.HyperscanProviders() {
+		metadata := d.Metadata(provider)
+		if metadata == nil {
+			problems = append(problems, provider.Name+": no metadata")
+			continue
+		}
+		if err := metadata.validate(); err != nil {
+			problems = append(problems, provider.Name+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid provider metadata:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// LoadValidatedDefaultConfig is LoadDefaultConfig, failing unless every
+// default provider has complete metadata, so a provider shipped without
+// metadata is caught when the configuration is loaded rather than when its
+// first finding is reported.
+func LoadValidatedDefaultConfig() (*Config, error) {
+	cfg, err := LoadDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateMetadata(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadValidatedCustomConfig is LoadCustomConfig, failing unless every one of
+// providers has complete metadata.
+func LoadValidatedCustomConfig(providers []*ProviderConfig) (*Config, error) {
+	cfg, err := LoadCustomConfig(providers)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateMetadata(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ParseProviderMetadata parses provider metadata in the format of the shipped
+// provider_metadata.yml, validating every entry.
+func ParseProviderMetadata(data []byte) (map[string]*ProviderMetadata, error) {
+	var file struct {
+		Providers map[string]*ProviderMetadata `yaml:"providers"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing provider metadata: %w", err)
+	}
+	for name, metadata := range file.Providers {
+		if metadata == nil {
+			return nil, fmt.Errorf("provider %s has empty metadata", name)
+		}
+		if err := metadata.validate(); err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+	}
+	return file.Providers, nil
+}
+
+func mustParseProviderMetadata(data []byte) map[string]*ProviderMetadata {
+	m, err := ParseProviderMetadata(data)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed suppressors.yml
+var suppressorsYAML []byte
+
+// SuppressionRegion is the part of a blob a suppressor match covers.
+type SuppressionRegion string
+
+const (
+	// SuppressMatch covers the suppressor's own span.
+	SuppressMatch SuppressionRegion = "match"
+	// SuppressLine covers the suppressor's span through to the end of its
+	// line, for suppressors that match only the key preceding a value.
+	SuppressLine SuppressionRegion = "line"
+)
+
+// Suppression declares a provider as a suppressor.
+type Suppression struct {
+	Provider string            `yaml:"provider"`
+	Region   SuppressionRegion `yaml:"region"`
+}
+
+// validate checks the region, defaulting it to SuppressMatch.
+func (s *Suppression) validate() error {
+	switch s.Region {
+	case SuppressMatch, SuppressLine:
+	case "":
+		s.Region = SuppressMatch
+	default:
+		return fmt.Errorf("suppressor %s has unknown region %q", s.Provider, s.Region)
+	}
+	return nil
+}
+
+// suppressions are the suppressor declarations shipped with the scanner,
+// keyed by provider name.
+var suppressions = mustParseSuppressions(suppressorsYAML)
+
+// Suppression returns how the provider's matches suppress other providers'
+// matches, or nil if the provider is not a suppressor.
+func (p *ProviderConfig) Suppression() *Suppression {
+	return suppressions[p.Name]
+}
+
+// IsSuppressor reports whether the provider's matches identify benign
+// content rather than secrets.
+func (p *ProviderConfig) IsSuppressor() bool {
+	return p.Suppression() != nil
+}
+
+// Suppression returns the suppressor declaration declared for the provider,
+// falling back to the shipped one, or nil if the provider is not a
+// suppressor.
+func (d *Declarations) Suppression(provider *ProviderConfig) *Suppression {
+	if d != nil {
+		if suppression, ok := d.suppression[provider.Name]; ok {
+			return suppression
+		}
+	}
+	return provider.Suppression()
+}
+
+// ParseSuppressions parses suppressor declarations in the format of the
+// shipped suppressors.yml.
+func ParseSuppressions(data []byte) (map[string]*Suppression, error) {
+	var file struct {
+		Suppressors []*Suppression `yaml:"suppressors"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing suppressors: %w", err)
+	}
+	byProvider := make(map[string]*Suppression, len(file.Suppressors))
+	for i, s := range file.Suppressors {
+		if s.Provider == "" {
+			return nil, fmt.Errorf("suppressor %d has no provider", i)
+		}
+		if _, ok := byProvider[s.Provider]; ok {
+			return nil, fmt.Errorf("suppressor %s is declared more than once", s.Provider)
+		}
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		byProvider[s.Provider] = s
+	}
+	return byProvider, nil
+}
+
+func mustParseSuppressions(data []byte) map[string]*Suppression {
+	s, err := ParseSuppressions(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// hashLength is the number of hex digits kept of version and pattern hashes;
+// enough to tell revisions apart while staying readable in logs.
+const hashLength = 16
+
+// PatternHash identifies the provider's pattern by its content, so findings
+// can be traced to the exact pattern that produced them.
+func (p *ProviderConfig) PatternHash() string {
+	sum := sha256.Sum256([]byte(p.Pattern))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// PatternHashes returns the pattern hash of every provider, keyed by provider
+// name.
+func (c *Config) PatternHashes() map[string]string {
+	hashes := make(map[string]string)
+	for _, provider := range c.HyperscanProviders() {
+		hashes[provider.Name] = provider.PatternHash()
+	}
+	return hashes
+}
+
+// Version identifies the configuration by its providers' names and patterns,
+// and by their shipped metadata, precedence and suppression, all of which
+// change the findings reported. It does not depend on the order providers
+// are listed in, so configurations with the same providers have the same
+// version wherever they were loaded from.
+func (c *Config) Version() string {
+	return (*Declarations)(nil).Version(c)
+}
+
+// Version is Config.Version with the providers' metadata, precedence and
+// suppression taken from the declarations before the shipped ones.
+func (d *Declarations) Version(cfg *Config) string {
+	entries := d.providerEntries(cfg)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(entries[name]))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:hashLength]
+}
+
+// providerEntries describes every provider of cfg by everything about it that
+// changes the findings reported, keyed by provider name.
+func (d *Declarations) providerEntries(cfg *Config) map[string]string {
+	entries := make(map[string]string)
+	for _, provider := range cfg.HyperscanProviders() {
+		var entry strings.Builder
+		entry.WriteString(provider.Name + "\x00" + provider.PatternHash() + "\n")
+		if m := d.Metadata(provider); m != nil {
+			fields := []string{m.Vendor, m.Name, string(m.Severity), string(m.Environment), m.DocsURL, m.Revocation}
+			entry.WriteString("metadata\x00" + strings.Join(fields, "\x00") + "\n")
+		}
+		if rule := d.PrecedenceRule(provider); rule != nil {
+			entry.WriteString("precedence\x00" + sortedList(rule.Supersedes) + "\x00" + sortedList(rule.SupersededBy) + "\x00" + sortedList(rule.Coexists) + "\n")
+		}
+		if suppression := d.Suppression(provider); suppression != nil {
+			entry.WriteString("suppression\x00" + string(suppression.Region) + "\n")
+		}
+		entries[provider.Name] = entry.String()
+	}
+	return entries
+}
+
+// sortedList joins a sorted copy of names, so the order a list is declared in
+// does not change the version.
+func sortedList(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// ConfigDiff lists the providers that differ between two configurations, each
+// sorted by name.
+type ConfigDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Changed are the providers present in both whose patterns, metadata,
+	// precedence or suppression differ.
+	Changed []string `json:"changed"`
+}
+
+// Empty reports whether the configurations have the same providers and
+// would report the same findings.
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffConfigs compares the providers of from and to, with their shipped
+// metadata, precedence and suppression.
+func DiffConfigs(from, to *Config) *ConfigDiff {
+	return DiffDeclaredConfigs(from, nil, to, nil)
+}
+
+// DiffDeclaredConfigs is DiffConfigs with the metadata, precedence and
+// suppression of each configuration's providers taken from its declarations
+// before the shipped ones.
+func DiffDeclaredConfigs(from *Config, fromDeclarations *Declarations, to *Config, toDeclarations *Declarations) *ConfigDiff {
+	before, after := fromDeclarations.providerEntries(from), toDeclarations.providerEntries(to)
+	diff := &ConfigDiff{}
+	for name, entry := range after {
+		previous, ok := before[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case previous != entry:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
@@ -0,0 +1,276 @@
+package hypercredscan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// DefaultReloadInterval is how often a ConfigManager checks its configuration
+// files for changes by default.
+const DefaultReloadInterval = 30 * time.Second
+
+// ErrManagerClosed is returned when acquiring a scanner from a closed
+// ConfigManager.
+var ErrManagerClosed = errors.New("config manager is closed")
+
+// Lease is a scanner and the filter to apply to its findings, borrowed for
+// the duration of a scan. Release must be called once the scan is done.
+type Lease struct {
+	Scanner *Scanner
+	Filter  findings.Filter
+	release func()
+}
+
+// Release returns the lease. It is safe to call more than once.
+func (l *Lease) Release() {
+	if l.release != nil {
+		l.release()
+	}
+}
+
+// ScannerSource hands out the scanner to use for a scan on behalf of
+// identity. Sources that scan everyone alike ignore the identity.
+type ScannerSource interface {
+	Acquire(identity Identity) (*Lease, error)
+}
+
+type staticSource struct {
+	scanner *Scanner
+	filter  findings.Filter
+}
+
+// StaticSource returns a ScannerSource always handing out scanner and filter.
+// The caller remains responsible for closing scanner.
+func StaticSource(scanner *Scanner, filter findings.Filter) ScannerSource {
+	return &staticSource{scanner: scanner, filter: filter}
+}
+
+func (s *staticSource) Acquire(Identity) (*Lease, error) {
+	return &Lease{Scanner: s.scanner, Filter: s.filter}, nil
+}
+
+// ConfigManagerOptions configures a ConfigManager. Zero fields take the
+// defaults noted.
+type ConfigManagerOptions struct {
+	// Interval defaults to DefaultReloadInterval.
+	Interval time.Duration
+	// ScannerOptions are applied to every scanner the manager builds.
+	ScannerOptions []ScannerOption
+	// Validate checks a newly loaded configuration before it is compiled,
+	// such as that a policy applied to its scanners names only its providers.
+	// An error from it rejects the configuration. Nil accepts every
+	// configuration.
+	Validate func(*config.Config) error
+	// Filter builds the filter for the scanner of a newly loaded
+	// configuration. It is part of the reload, so an error from it rejects
+	// the configuration. Nil means findings are not filtered.
+	Filter func(*Scanner) (findings.Filter, error)
+}
+
+// generation is one loaded configuration. Its scanner is closed once it has
+// been replaced and the last lease on it released.
+type generation struct {
+	scanner  *Scanner
+	filter   findings.Filter
+	digest   string
+	inflight sync.WaitGroup
+}
+
+// ConfigManager keeps a scanner built from the provider configuration at a
+// file or directory, see config.LoadConfigPath, and replaces it when the
+// configuration changes. New configurations are loaded and compiled in the
+// background; scans leased before a swap finish on the database they started
+// with. A configuration that fails to load, validate or compile is reported
+// to the ExceptionReporter and the current one is kept.
+type ConfigManager struct {
+	path     string
+	reporter ExceptionReporter
+	opts     ConfigManagerOptions
+
+	// reloadMu serializes reloads, so concurrent reloads cannot install
+	// configurations out of order.
+	reloadMu sync.Mutex
+
+	// rejected is the digest of the last configuration that failed to load,
+	// so polling reports a broken configuration once rather than on every
+	// tick.
+	rejected string
+
+	mu      sync.RWMutex
+	current *generation
+	closed  bool
+}
+
+// NewConfigManager loads the configuration at path. Unlike later reloads, a
+// failure to load it is returned rather than reported.
+func NewConfigManager(path string, reporter ExceptionReporter, opts ConfigManagerOptions) (*ConfigManager, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultReloadInterval
+	}
+	m := &ConfigManager{path: path, reporter: reporter, opts: opts}
+	digest, err := m.digest()
+	if err != nil {
+		return nil, err
+	}
+	g, err := m.load(digest)
+	if err != nil {
+		return nil, err
+	}
+	m.current = g
+	return m, nil
+}
+
+// Acquire leases the current scanner, whatever the identity. It implements
+// ScannerSource.
+func (m *ConfigManager) Acquire(Identity) (*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	g := m.current
+	g.inflight.Add(1)
+	return &Lease{Scanner: g.scanner, Filter: g.filter, release: sync.OnceFunc(g.inflight.Done)}, nil
+}
+
+// Run checks the configuration for changes every interval until ctx is done.
+func (m *ConfigManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = m.reload(ctx, false)
+		}
+	}
+}
+
+// Reload loads the configuration now, even if its files have not changed,
+// for callers reloading on a signal. A failed reload is reported to the
+// ExceptionReporter as well as returned.
+func (m *ConfigManager) Reload(ctx context.Context) error {
+	return m.reload(ctx, true)
+}
+
+func (m *ConfigManager) reload(ctx context.Context, force bool) error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	digest, err := m.digest()
+	if err != nil {
+		// Identify the failure instead, so a missing file is reported once.
+		digest = "unreadable: " + err.Error()
+	}
+	if !force {
+		m.mu.RLock()
+		unchanged := m.current.digest == digest || m.rejected == digest
+		m.mu.RUnlock()
+		if unchanged {
+			return nil
+		}
+	}
+	var g *generation
+	if err == nil {
+		g, err = m.load(digest)
+	}
+	if err != nil {
+		m.rejected = digest
+		err = fmt.Errorf("reloading provider configuration from %s: %w", m.path, err)
+		m.reporter.Report(ctx, err, map[string]interface{}{"path": m.path})
+		return err
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		_ = g.scanner.Close()
+		return ErrManagerClosed
+	}
+	old := m.current
+	m.current = g
+	m.mu.Unlock()
+	m.rejected = ""
+	go m.retire(context.WithoutCancel(ctx), old)
+	return nil
+}
+
+// load builds a generation from the configuration at the manager's path.
+func (m *ConfigManager) load(digest string) (*generation, error) {
+	cfg, declarations, err := config.LoadConfigPath(m.path)
+	if err != nil {
+		return nil, err
+	}
+	if m.opts.Validate != nil {
+		if err := m.opts.Validate(cfg); err != nil {
+			return nil, err
+		}
+	}
+	opts := append([]ScannerOption{WithDeclarations(declarations)}, m.opts.ScannerOptions...)
+	scanner, err := NewScanner(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var filter findings.Filter
+	if m.opts.Filter != nil {
+		if filter, err = m.opts.Filter(scanner); err != nil {
+			_ = scanner.Close()
+			return nil, err
+		}
+	}
+	return &generation{scanner: scanner, filter: filter, digest: digest}, nil
+}
+
+// retire closes g's scanner once its last lease is released.
+func (m *ConfigManager) retire(ctx context.Context, g *generation) {
+	g.inflight.Wait()
+	if err := g.scanner.Close(); err != nil {
+		m.reporter.Report(ctx, fmt.Errorf("closing replaced scanner: %w", err), map[string]interface{}{"path": m.path})
+	}
+}
+
+// digest identifies the current content of the configuration files, so
+// polling only reloads when something changed.
+func (m *ConfigManager) digest() (string, error) {
+	files, err := config.ConfigFiles(m.path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Close stops handing out scanners and closes the current one once its
+// leases are released, waiting for them.
+func (m *ConfigManager) Close() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	g := m.current
+	m.mu.Unlock()
+	g.inflight.Wait()
+	return g.scanner.Close()
+}
@@ -0,0 +1,239 @@
+package hypercredscan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	adafruitProviderFile = `providers:
+  - name: ADAFRUIT_AIO_KEY
+    pattern: 'aio_[a-zA-Z0-9]{28}'
+`
+	exampleProviderFile = `providers:
+  - name: RELOAD_EXAMPLE_TOKEN
+    pattern: 'rex_[a-z0-9]{16}'
+    metadata:
+      vendor: Example
+      name: Example Token
+      severity: medium
+      environment: live
+`
+	adafruitToken      = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+	reloadExampleToken = "rex_0123456789abcdef"
+)
+
+// recordingReporter collects the errors reported to it.
+type recordingReporter struct {
+	mu     sync.Mutex
+	errors []error
+}
+
+func (r *recordingReporter) Report(_ context.Context, err error, _ map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, err)
+}
+
+func (r *recordingReporter) reported() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errors...)
+}
+
+func writeProviderFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+// leasedProviders scans content with a fresh lease and returns the providers
+// found.
+func leasedProviders(t *testing.T, m *ConfigManager, content string) []string {
+	t.Helper()
+	lease, err := m.Acquire(Identity{})
+	require.NoError(t, err)
+	defer lease.Release()
+	found, err := lease.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(content)})
+	require.NoError(t, err)
+	var providers []string
+	for _, f := range found {
+		providers = append(providers, f.Provider)
+	}
+	return providers
+}
+
+func TestConfigManagerReloadsChangedConfig(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	writeProviderFile(t, path, adafruitProviderFile)
+	reporter := &recordingReporter{}
+	m, err := NewConfigManager(path, reporter, ConfigManagerOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Close()) })
+
+	content := adafruitToken + " " + reloadExampleToken
+	require.Equal(t, []string{"ADAFRUIT_AIO_KEY"}, leasedProviders(t, m, content))
+
+	// A scan in flight across the swap keeps the old database.
+	old, err := m.Acquire(Identity{})
+	require.NoError(t, err)
+	writeProviderFile(t, path, exampleProviderFile)
+	require.NoError(t, m.reload(context.Background(), false))
+	require.Equal(t, []string{"RELOAD_EXAMPLE_TOKEN"}, leasedProviders(t, m, content))
+
+	found, err := old.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(content)})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, "ADAFRUIT_AIO_KEY", found[0].Provider)
+	old.Release()
+	require.Eventually(t, func() bool {
+		_, err := old.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(content)})
+		return err != nil
+	}, time.Second, 10*time.Millisecond, "replaced scanner is closed once released")
+	require.Empty(t, reporter.reported())
+}
+
+func TestConfigManagerKeepsConfigOnFailedReload(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	writeProviderFile(t, path, adafruitProviderFile)
+	reporter := &recordingReporter{}
+	m, err := NewConfigManager(path, reporter, ConfigManagerOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Close()) })
+
+	for _, broken := range []string{
+		"providers: [",
+		"providers:\n  - name: NO_METADATA\n    pattern: 'x{4}'\n",
+		"providers:\n  - name: ADAFRUIT_AIO_KEY\n    pattern: '(unclosed'\n",
+	} {
+		writeProviderFile(t, path, broken)
+		require.Error(t, m.reload(context.Background(), false))
+		// The same broken content is only reported once.
+		require.NoError(t, m.reload(context.Background(), false))
+		require.Equal(t, []string{"ADAFRUIT_AIO_KEY"}, leasedProviders(t, m, adafruitToken))
+	}
+	require.Len(t, reporter.reported(), 3)
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, m.reload(context.Background(), false))
+	require.Len(t, reporter.reported(), 4)
+
+	// Forcing a reload retries even unchanged content.
+	require.Error(t, m.Reload(context.Background()))
+	require.Len(t, reporter.reported(), 5)
+}
+
+func TestConfigManagerScopesDeclarationsToTheirConfig(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	writeProviderFile(t, path, exampleProviderFile)
+	m, err := NewConfigManager(path, &recordingReporter{}, ConfigManagerOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Close()) })
+
+	vendor := func() string {
+		lease, err := m.Acquire(Identity{})
+		require.NoError(t, err)
+		defer lease.Release()
+		found, err := lease.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(reloadExampleToken)})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		return found[0].ProviderMetadata().Vendor
+	}
+	require.Equal(t, "Example", vendor())
+
+	// Metadata declared by a rejected configuration is used nowhere.
+	writeProviderFile(t, path, strings.Replace(exampleProviderFile, "vendor: Example", "vendor: Rejected", 1)+
+		"  - name: BROKEN_TOKEN\n    pattern: '(unclosed'\n")
+	require.Error(t, m.reload(context.Background(), false))
+	require.Equal(t, "Example", vendor())
+	require.Nil(t, (&config.ProviderConfig{Name: "RELOAD_EXAMPLE_TOKEN"}).Metadata(), "declared metadata is not shipped metadata")
+}
+
+func TestConfigManagerValidatesEveryConfig(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	writeProviderFile(t, path, exampleProviderFile)
+	policy, err := config.ParsePolicy([]byte("opt_in: [RELOAD_EXAMPLE_TOKEN]\n"))
+	require.NoError(t, err)
+	reporter := &recordingReporter{}
+	opts := ConfigManagerOptions{Validate: func(cfg *config.Config) error {
+		return policy.Validate(cfg.HyperscanProviders())
+	}}
+	m, err := NewConfigManager(path, reporter, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Close()) })
+
+	// The policy names a provider the new configuration drops.
+	writeProviderFile(t, path, adafruitProviderFile)
+	require.ErrorContains(t, m.reload(context.Background(), false), "RELOAD_EXAMPLE_TOKEN")
+	require.Len(t, reporter.reported(), 1)
+	require.Equal(t, []string{"RELOAD_EXAMPLE_TOKEN"}, leasedProviders(t, m, adafruitToken+" "+reloadExampleToken))
+
+	_, err = NewConfigManager(path, reporter, opts)
+	require.Error(t, err)
+}
+
+func TestConfigManagerLoadsDirectory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeProviderFile(t, filepath.Join(dir, "10-adafruit.yml"), adafruitProviderFile)
+	writeProviderFile(t, filepath.Join(dir, "20-example.yaml"), exampleProviderFile)
+	writeProviderFile(t, filepath.Join(dir, "README.md"), "not a provider file")
+	reporter := &recordingReporter{}
+	m, err := NewConfigManager(dir, reporter, ConfigManagerOptions{Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Close()) })
+	require.ElementsMatch(t, []string{"ADAFRUIT_AIO_KEY", "RELOAD_EXAMPLE_TOKEN"}, leasedProviders(t, m, adafruitToken+" "+reloadExampleToken))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	require.NoError(t, os.Remove(filepath.Join(dir, "20-example.yaml")))
+	require.Eventually(t, func() bool {
+		return len(leasedProviders(t, m, adafruitToken+" "+reloadExampleToken)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// A provider declared in two files is rejected.
+	writeProviderFile(t, filepath.Join(dir, "30-duplicate.yml"), adafruitProviderFile)
+	require.Eventually(t, func() bool {
+		return len(reporter.reported()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Contains(t, reporter.reported()[0].Error(), "already declared in")
+}
+
+func TestConfigManagerClose(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	writeProviderFile(t, path, adafruitProviderFile)
+	m, err := NewConfigManager(path, &recordingReporter{}, ConfigManagerOptions{})
+	require.NoError(t, err)
+
+	lease, err := m.Acquire(Identity{})
+	require.NoError(t, err)
+	closed := make(chan error)
+	go func() { closed <- m.Close() }()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a lease was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	lease.Release()
+	require.NoError(t, <-closed)
+	_, err = m.Acquire(Identity{})
+	require.ErrorIs(t, err, ErrManagerClosed)
+
+	_, err = NewConfigManager(filepath.Join(t.TempDir(), "missing.yml"), &recordingReporter{}, ConfigManagerOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package hypercredscan
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// SecretMode controls how exporters represent a finding's secret value.
+type SecretMode int
+
+const (
+	// SecretsOmitted leaves the secret out entirely. It is the default, so
+	// exported files can be shared without leaking credentials; the keyed
+	// fingerprint still identifies the secret. An unkeyed hash is never
+	// written, since a short or structured secret is easily recovered from it.
+	SecretsOmitted SecretMode = iota
+	// SecretsRaw writes the secret itself. Only use it when the output is
+	// handled as sensitively as the scanned content.
+	SecretsRaw
+)
+
+// exportedFinding is the flat representation shared by the JSON Lines and CSV
+// exporters.
+type exportedFinding struct {
+	Provider    string               `json:"provider"`
+	BlobSHA     string               `json:"blob_sha"`
+	Path        string               `json:"path"`
+	Start       uint64               `json:"start"`
+	End         uint64               `json:"end"`
+	Line        int                  `json:"line"`
+	Column      int                  `json:"column"`
+	Fingerprint string               `json:"fingerprint"`
+	Secret      string               `json:"secret,omitempty"`
+	Decisions   []findings.Decision  `json:"decisions"`
+	LikelyTest  bool                 `json:"likely_test"`
+	Confidence  float64              `json:"confidence"`
+	Severity    string               `json:"severity"`
+	Vendor      string               `json:"vendor"`
+	Name        string               `json:"provider_name"`
+	Environment string               `json:"environment"`
+	DocsURL     string               `json:"docs_url,omitempty"`
+	Revocation  string               `json:"revocation,omitempty"`
+	Annotations map[string]string    `json:"annotations,omitempty"`
+	Validation  *findings.Validation `json:"validation,omitempty"`
+	// ConfigVersion and PatternHash trace the finding to the provider
+	// configuration that produced it.
+	ConfigVersion string `json:"config_version,omitempty"`
+	PatternHash   string `json:"pattern_hash,omitempty"`
+	// Alternatives are the overlapping findings the finding superseded.
+	Alternatives []findings.Alternative `json:"alternatives,omitempty"`
+}
+
+func newExportedFinding(f *findings.Finding, mode SecretMode) exportedFinding {
+	metadata := f.ProviderMetadata()
+	e := exportedFinding{
+		Provider:    f.Provider,
+		BlobSHA:     f.BlobSHA,
+		Path:        f.Path,
+		Start:       f.Start,
+		End:         f.End,
+		Line:        f.Line,
+		Column:      f.Column,
+		Fingerprint: f.Fingerprint,
+		Decisions:   f.Decisions,
+		LikelyTest:  f.LikelyTest,
+		Confidence:  f.Confidence,
+		Severity:    string(f.Severity),
+		Vendor:      metadata.Vendor,
+		Name:        metadata.Name,
+		Environment: string(metadata.Environment),
+		DocsURL:     metadata.DocsURL,
+		Revocation:  metadata.Revocation,
+		Annotations: f.Annotations,
+		Validation:  f.Validation,
+
+		ConfigVersion: f.ConfigVersion,
+		PatternHash:   f.PatternHash,
+		Alternatives:  f.Alternatives,
+	}
+	if e.Decisions == nil {
+		e.Decisions = []findings.Decision{}
+	}
+	if mode == SecretsRaw {
+		e.Secret = string(f.Secret)
+	}
+	return e
+}
+
+// JSONLinesMatchProcessor writes each finding as a single line JSON object as
+// soon as it is processed, for piping into tools such as jq.
+type JSONLinesMatchProcessor struct {
+	mode SecretMode
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLinesMatchProcessor returns a JSONLinesMatchProcessor writing to w.
+func NewJSONLinesMatchProcessor(w io.Writer, mode SecretMode) findings.Processor {
+	return &JSONLinesMatchProcessor{mode: mode, enc: json.NewEncoder(w)}
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *JSONLinesMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	e := newExportedFinding(f, p.mode)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enc.Encode(e)
+}
+
+// Flush implements findings.Processor.
+func (p *JSONLinesMatchProcessor) Flush() error {
+	return nil
+}
+
+// csvColumns is the header of CSV exports. Columns are only ever appended, so
+// spreadsheets and scripts can rely on their position.
+var csvColumns = []string{
+	"provider", "blob_sha", "path", "start", "end", "line", "column",
+	"fingerprint", "secret", "decisions", "likely_test",
+	"confidence", "severity", "vendor", "provider_name", "environment",
+	"docs_url", "revocation", "annotations", "validation",
+	"config_version", "pattern_hash",
+}
+
+// CSVMatchProcessor writes a header row followed by one row per finding. The
+// secret column is empty unless the SecretMode is SecretsRaw. Decisions are
+// written as "filter:outcome" pairs and annotations as "key=value" pairs, each
+// separated by semicolons; the validation column holds the validation status,
+// if any.
+type CSVMatchProcessor struct {
+	mode SecretMode
+
+	mu            sync.Mutex
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVMatchProcessor returns a CSVMatchProcessor writing to w.
+func NewCSVMatchProcessor(w io.Writer, mode SecretMode) findings.Processor {
+	return &CSVMatchProcessor{mode: mode, w: csv.NewWriter(w)}
+}
+
+func (p *CSVMatchProcessor) writeHeader() error {
+	if p.headerWritten {
+		return nil
+	}
+	p.headerWritten = true
+	return p.w.Write(csvColumns)
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *CSVMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	e := newExportedFinding(f, p.mode)
+	decisions := make([]string, len(e.Decisions))
+	for i, d := range e.Decisions {
+		decisions[i] = d.Filter + ":" + string(d.Outcome)
+	}
+	annotations := make([]string, 0, len(e.Annotations))
+	for key, value := range e.Annotations {
+		annotations = append(annotations, key+"="+value)
+	}
+	sort.Strings(annotations)
+	validation := ""
+	if e.Validation != nil {
+		validation = string(e.Validation.Status)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	return p.w.Write([]string{
+		e.Provider,
+		e.BlobSHA,
+		e.Path,
+		strconv.FormatUint(e.Start, 10),
+		strconv.FormatUint(e.End, 10),
+		strconv.Itoa(e.Line),
+		strconv.Itoa(e.Column),
+		e.Fingerprint,
+		e.Secret,
+		strings.Join(decisions, ";"),
+		strconv.FormatBool(e.LikelyTest),
+		strconv.FormatFloat(e.Confidence, 'f', 2, 64),
+		e.Severity,
+		e.Vendor,
+		e.Name,
+		e.Environment,
+		e.DocsURL,
+		e.Revocation,
+		strings.Join(annotations, ";"),
+		validation,
+		e.ConfigVersion,
+		e.PatternHash,
+	})
+}
+
+// Flush implements findings.Processor.
+func (p *CSVMatchProcessor) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	p.w.Flush()
+	return p.w.Error()
+}
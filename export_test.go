@@ -0,0 +1,128 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func exportFixture() *findings.Finding {
+	f := &findings.Finding{
+		Provider:   "ADAFRUIT_AIO_KEY",
+		BlobSHA:    "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Path:       "config/app.yml",
+		Start:      15,
+		End:        47,
+		Line:       2,
+		Column:     8,
+		Secret:     []byte("aio_FMBo07xPM4e0Aj3eYjO23blItBvS"),
+		LikelyTest: true,
+		Metadata:   (&config.ProviderConfig{Name: "ADAFRUIT_AIO_KEY"}).Metadata(),
+		Severity:   config.SeverityHigh,
+	}
+	f.Fingerprint = findings.NewFingerprinter(nil).Fingerprint(f.Provider, f.Secret)
+	f.Decide("placeholder", findings.OutcomeFlag, "likely test value")
+	f.Validation = &findings.Validation{Status: findings.ValidationInactive, Detail: "status 401"}
+	f.ConfigVersion = "5d41402abc4b2a76"
+	f.PatternHash = "b9ea4cf6a6c3d0e1"
+	return f
+}
+
+func TestJSONLinesMatchProcessor(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		mode   SecretMode
+		secret bool
+	}{
+		{mode: SecretsOmitted},
+		{mode: SecretsRaw, secret: true},
+	} {
+		var out bytes.Buffer
+		processor := NewJSONLinesMatchProcessor(&out, tc.mode)
+		require.NoError(t, processor.ProcessFinding(context.Background(), exportFixture()))
+		require.NoError(t, processor.ProcessFinding(context.Background(), exportFixture()))
+		require.NoError(t, processor.Flush())
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+
+		var got map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+		require.Equal(t, "ADAFRUIT_AIO_KEY", got["provider"])
+		require.Equal(t, "config/app.yml", got["path"])
+		require.Equal(t, float64(2), got["line"])
+		require.Equal(t, exportFixture().Fingerprint, got["fingerprint"])
+		require.Len(t, got["decisions"], 1)
+		require.Equal(t, true, got["likely_test"])
+		require.Equal(t, "high", got["severity"])
+		require.Equal(t, "Adafruit AIO Key", got["provider_name"])
+		require.Equal(t, "inactive", got["validation"].(map[string]interface{})["status"])
+		require.Equal(t, "5d41402abc4b2a76", got["config_version"])
+		require.Equal(t, "b9ea4cf6a6c3d0e1", got["pattern_hash"])
+		_, hasSecret := got["secret"]
+		require.Equal(t, tc.secret, hasSecret)
+		if !tc.secret {
+			require.NotContains(t, out.String(), "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+			sum := sha256.Sum256(exportFixture().Secret)
+			require.NotContains(t, out.String(), hex.EncodeToString(sum[:]), "unkeyed hashes are never exported")
+		}
+	}
+}
+
+func TestJSONMatchProcessor(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	processor := NewJSONMatchProcessor(&out)
+	f := exportFixture()
+	metadata := *f.Metadata
+	metadata.Revocation = "Regenerate the key on io.adafruit.com."
+	f.Metadata = &metadata
+	require.NoError(t, processor.ProcessFinding(context.Background(), f))
+	require.NoError(t, processor.Flush())
+
+	var got []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, exportFixture().Fingerprint, got[0]["fingerprint"])
+	require.Equal(t, "live", got[0]["environment"])
+	require.Equal(t, metadata.Revocation, got[0]["revocation"])
+	require.NotContains(t, out.String(), "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+}
+
+func TestCSVMatchProcessor(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	processor := NewCSVMatchProcessor(&out, SecretsOmitted)
+	require.NoError(t, processor.ProcessFinding(context.Background(), exportFixture()))
+	require.NoError(t, processor.Flush())
+
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, csvColumns, records[0])
+	require.Equal(t, []string{"ADAFRUIT_AIO_KEY", "4b825dc642cb6eb9a060e54bf8d69288fbee4904", "config/app.yml", "15", "47", "2", "8"}, records[1][:7])
+	require.Equal(t, exportFixture().Fingerprint, records[1][7])
+	require.Empty(t, records[1][8])
+	require.Equal(t, "placeholder:flag", records[1][9])
+	require.Equal(t, "true", records[1][10])
+	require.Equal(t, []string{"high", "Adafruit", "Adafruit AIO Key", "live"}, records[1][12:16])
+	require.Equal(t, []string{"inactive", "5d41402abc4b2a76", "b9ea4cf6a6c3d0e1"}, records[1][19:])
+}
+
+func TestCSVMatchProcessorWritesHeaderWithoutFindings(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	processor := NewCSVMatchProcessor(&out, SecretsOmitted)
+	require.NoError(t, processor.Flush())
+	require.Equal(t, strings.Join(csvColumns, ",")+"\n", out.String())
+}
@@ -0,0 +1,34 @@
+package findings
+
+import "context"
+
+// Filter decides which of the findings in a blob are reported. It returns the
+// findings to keep and records a Decision on every finding it suppresses or
+// flags. Filters see all findings of a blob at once, so they can weigh
+// findings against each other.
+type Filter interface {
+	Name() string
+	Filter(ctx context.Context, blob *Blob, found []*Finding) []*Finding
+}
+
+type chain []Filter
+
+// Chain returns a Filter that applies filters in order, each seeing only what
+// the previous ones kept.
+func Chain(filters ...Filter) Filter {
+	return chain(filters)
+}
+
+func (c chain) Name() string {
+	return "chain"
+}
+
+func (c chain) Filter(ctx context.Context, blob *Blob, found []*Finding) []*Finding {
+	for _, f := range c {
+		if len(found) == 0 {
+			break
+		}
+		found = f.Filter(ctx, blob, found)
+	}
+	return found
+}
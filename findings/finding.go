@@ -0,0 +1,219 @@
+// Package findings holds the types shared by everything that consumes
+// scanner output: the scanner itself, the filters that prune results and the
+// match processors that report them.
+package findings
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+// Blob is a piece of content handed to the scanner, identified by its git
+// blob SHA and, when known, the path it was read from.
+type Blob struct {
+	SHA     string
+	Path    string
+	Content []byte
+}
+
+// Finding is a single provider match located within a blob.
+type Finding struct {
+	Provider string
+	BlobSHA  string
+	Path     string
+
+	// ConfigVersion and PatternHash identify the provider configuration and
+	// the provider pattern that produced the finding; see config.Config's
+	// Version and config.ProviderConfig's PatternHash.
+	ConfigVersion string
+	PatternHash   string
+
+	// Metadata describes the provider. Severity starts as the provider's
+	// severity and may be lowered by processors that learn more about the
+	// secret.
+	Metadata *config.ProviderMetadata
+	Severity config.Severity
+
+	// Start and End are byte offsets of the match within the blob content.
+	Start uint64
+	End   uint64
+
+	// Line and Column are 1-based and point at the first byte of the match.
+	Line   int
+	Column int
+
+	Secret []byte
+	// Fingerprint identifies the provider and secret independently of where
+	// the secret was found; see Fingerprinter.
+	Fingerprint string
+	// Snippet is the line around the match with the secret redacted.
+	Snippet string
+
+	// LikelyTest is set when the secret looks like an example or placeholder
+	// rather than a live credential.
+	LikelyTest bool
+
+	// Confidence scores how likely the finding is a live secret, from 0 to 1.
+	// It is set by the confidence scorer and is zero until then.
+	Confidence float64
+
+	// Annotations are facts processors learned about the secret, such as a
+	// token's issuer, keyed by a dotted name like "jwt.iss".
+	Annotations map[string]string
+
+	// Alternatives are the overlapping findings of other providers that this
+	// finding superseded.
+	Alternatives []Alternative
+
+	// Decisions records, in order, what each filter that looked at the finding
+	// decided.
+	Decisions []Decision
+
+	// Validation is the result of checking the secret with its vendor. It is
+	// nil unless the finding passed through a validating processor.
+	Validation *Validation
+}
+
+// Outcome is what a filter decided about a finding.
+type Outcome string
+
+const (
+	// OutcomePass means the filter let the finding through unchanged.
+	OutcomePass Outcome = "pass"
+	// OutcomeFlag means the finding was let through but marked, for example as
+	// a likely test value.
+	OutcomeFlag Outcome = "flag"
+	// OutcomeSuppress means the finding was dropped.
+	OutcomeSuppress Outcome = "suppress"
+)
+
+// Decision records what a filter did with a finding and why.
+type Decision struct {
+	Filter  string  `json:"filter"`
+	Outcome Outcome `json:"outcome"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// Alternative is a finding suppressed in favour of an overlapping finding of
+// a provider with precedence over its own.
+type Alternative struct {
+	Provider    string `json:"provider"`
+	Start       uint64 `json:"start"`
+	End         uint64 `json:"end"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// ValidationStatus is what checking a secret with its vendor revealed.
+type ValidationStatus string
+
+const (
+	// ValidationActive means the vendor accepted the secret.
+	ValidationActive ValidationStatus = "active"
+	// ValidationInactive means the vendor rejected the secret, typically
+	// because it was revoked or has expired.
+	ValidationInactive ValidationStatus = "inactive"
+	// ValidationUnknown means the check completed without a clear answer, or
+	// was skipped.
+	ValidationUnknown ValidationStatus = "unknown"
+	// ValidationError means the check could not be completed.
+	ValidationError ValidationStatus = "error"
+)
+
+// Validation records the outcome of checking a finding's secret.
+type Validation struct {
+	Status    ValidationStatus `json:"status"`
+	Detail    string           `json:"detail,omitempty"`
+	CheckedAt time.Time        `json:"checked_at"`
+}
+
+// ProviderMetadata returns the finding's provider metadata, or the zero value
+// if the finding has none.
+func (f *Finding) ProviderMetadata() config.ProviderMetadata {
+	if f.Metadata == nil {
+		return config.ProviderMetadata{}
+	}
+	return *f.Metadata
+}
+
+// Annotate records a fact about the secret.
+func (f *Finding) Annotate(key, value string) {
+	if f.Annotations == nil {
+		f.Annotations = make(map[string]string)
+	}
+	f.Annotations[key] = value
+}
+
+// Decide appends a decision to the finding.
+func (f *Finding) Decide(filter string, outcome Outcome, reason string) {
+	f.Decisions = append(f.Decisions, Decision{Filter: filter, Outcome: outcome, Reason: reason})
+}
+
+// Processor receives findings that survived filtering. Implementations must be
+// safe for concurrent use, as blobs are scanned in parallel.
+type Processor interface {
+	ProcessFinding(ctx context.Context, f *Finding) error
+	// Flush is called once after the last finding has been processed.
+	Flush() error
+}
+
+// Locate returns the 1-based line and column of offset within content.
+func Locate(content []byte, offset uint64) (line, column int) {
+	if offset > uint64(len(content)) {
+		offset = uint64(len(content))
+	}
+	head := content[:offset]
+	line = bytes.Count(head, []byte{'\n'}) + 1
+	column = int(offset) - (bytes.LastIndexByte(head, '\n') + 1) + 1
+	return line, column
+}
+
+// RedactedSecret returns the secret with everything but a short prefix masked,
+// suitable for human-readable output.
+func (f *Finding) RedactedSecret() string {
+	const visible = 4
+	if len(f.Secret) <= visible*2 {
+		return string(bytes.Repeat([]byte{'*'}, len(f.Secret)))
+	}
+	return string(f.Secret[:visible]) + string(bytes.Repeat([]byte{'*'}, len(f.Secret)-visible))
+}
+
+// snippetContext is how many bytes on either side of a match Snippet keeps.
+const snippetContext = 80
+
+// Snippet returns the text surrounding f within content, limited to the line
+// the match is on and snippetContext bytes either side, with the secret
+// itself redacted. Every other finding of found within the snippet is masked
+// entirely, so the context never shows a secret either.
+func Snippet(f *Finding, content []byte, found []*Finding) string {
+	start, end := f.Start, f.End
+	if end > uint64(len(content)) || start > end {
+		return ""
+	}
+	from := bytes.LastIndexByte(content[:start], '\n') + 1
+	if int(start)-from > snippetContext {
+		from = int(start) - snippetContext
+	}
+	to := len(content)
+	if i := bytes.IndexByte(content[end:], '\n'); i >= 0 {
+		to = int(end) + i
+	}
+	if to-int(end) > snippetContext {
+		to = int(end) + snippetContext
+	}
+
+	snippet := append([]byte(nil), content[from:to]...)
+	copy(snippet[int(start)-from:], f.RedactedSecret())
+	for _, other := range found {
+		if other == f || other.End > uint64(len(content)) {
+			continue
+		}
+		lo, hi := max(int(other.Start), from), min(int(other.End), to)
+		for i := lo; i < hi; i++ {
+			snippet[i-from] = '*'
+		}
+	}
+	return string(snippet)
+}
@@ -0,0 +1,64 @@
+package findings
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DefaultFingerprintKey is used when no key is configured. Fingerprints made
+// with it can be recomputed by anyone who guesses the secret, so deployments
+// that share fingerprints outside the scanner should set their own key.
+var DefaultFingerprintKey = []byte("hypercredscan-fingerprint-v1")
+
+// Fingerprinter computes stable, keyed identifiers for secrets. The same
+// provider and secret always produce the same fingerprint under the same key,
+// wherever the secret was found, so findings can be deduplicated across blobs
+// and repositories, and allowlists or revocation systems can match secrets
+// without storing them.
+type Fingerprinter struct {
+	key []byte
+}
+
+// NewFingerprinter returns a Fingerprinter using key, or DefaultFingerprintKey
+// if key is empty.
+func NewFingerprinter(key []byte) *Fingerprinter {
+	if len(key) == 0 {
+		key = DefaultFingerprintKey
+	}
+	return &Fingerprinter{key: append([]byte(nil), key...)}
+}
+
+// Fingerprint returns the hex encoded HMAC-SHA256 of the provider name and the
+// normalized secret.
+func (fp *Fingerprinter) Fingerprint(provider string, secret []byte) string {
+	mac := hmac.New(sha256.New, fp.key)
+	mac.Write([]byte(provider))
+	mac.Write([]byte{0})
+	mac.Write(NormalizeSecret(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NormalizeSecret strips the incidental differences between occurrences of the
+// same secret: surrounding whitespace and quotes, CRLF line endings and
+// trailing whitespace on the lines of multi-line secrets such as private keys.
+// Case is preserved, as tokens are case sensitive.
+func NormalizeSecret(secret []byte) []byte {
+	s := bytes.TrimSpace(secret)
+	for len(s) >= 2 && isQuote(s[0]) && s[len(s)-1] == s[0] {
+		s = bytes.TrimSpace(s[1 : len(s)-1])
+	}
+	if bytes.IndexByte(s, '\n') < 0 {
+		return s
+	}
+	lines := bytes.Split(bytes.ReplaceAll(s, []byte("\r\n"), []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " \t\r")
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+func isQuote(b byte) bool {
+	return b == '"' || b == '\'' || b == '`'
+}
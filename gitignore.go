@@ -0,0 +1,116 @@
+package hypercredscan
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/internal/pathglob"
+)
+
+// gitignoreRule is a single pattern line from a .gitignore file.
+type gitignoreRule struct {
+	// base is the slash separated directory, relative to the walk root, that
+	// contains the .gitignore file the rule was read from.
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// gitignore is an ordered set of rules. Later rules take precedence over
+// earlier ones, matching git's semantics.
+type gitignore struct {
+	rules []gitignoreRule
+}
+
+// loadGitignore reads the .gitignore file at file and appends its rules to a
+// copy of parent, scoped to base. A missing file yields parent unchanged.
+func loadGitignore(parent *gitignore, file, base string) (*gitignore, error) {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return parent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gi := &gitignore{}
+	if parent != nil {
+		gi.rules = append(gi.rules, parent.rules...)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(scanner.Text(), base); ok {
+			gi.rules = append(gi.rules, rule)
+		}
+	}
+	return gi, scanner.Err()
+}
+
+func parseGitignoreLine(line, base string) (gitignoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	// A slash anywhere but the end anchors the pattern to the .gitignore's
+	// directory; otherwise it matches at any depth.
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// ignored reports whether the slash separated path rel, relative to the walk
+// root, is excluded.
+func (gi *gitignore) ignored(rel string, isDir bool) bool {
+	if gi == nil {
+		return false
+	}
+	ignored := false
+	for _, rule := range gi.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (r gitignoreRule) matches(rel string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.base+"/")
+	}
+	if !r.anchored {
+		return pathglob.Match(r.pattern, path.Base(rel))
+	}
+	return pathglob.Match(r.pattern, rel)
+}
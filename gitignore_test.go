@@ -0,0 +1,52 @@
+package hypercredscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitignore(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(`
+# comment
+*.log
+!keep.log
+/build
+vendor/
+docs/**/generated
+`), 0o600))
+
+	root, err := loadGitignore(nil, filepath.Join(dir, ".gitignore"), "")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", ".gitignore"), []byte("secret.txt\n"), 0o600))
+	sub, err := loadGitignore(root, filepath.Join(dir, "sub", ".gitignore"), "sub")
+	require.NoError(t, err)
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{path: "app.log", ignored: true},
+		{path: "nested/dir/app.log", ignored: true},
+		{path: "keep.log", ignored: false},
+		{path: "build", isDir: true, ignored: true},
+		{path: "src/build", isDir: true, ignored: false},
+		{path: "src/vendor", isDir: true, ignored: true},
+		{path: "vendor", isDir: false, ignored: false},
+		{path: "docs/generated", isDir: true, ignored: true},
+		{path: "docs/a/b/generated", isDir: true, ignored: true},
+		{path: "sub/secret.txt", ignored: true},
+		{path: "secret.txt", ignored: false},
+		{path: "main.go", ignored: false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.ignored, sub.ignored(c.path, c.isDir), c.path)
+	}
+}
@@ -3,7 +3,9 @@
 package hypercredscan
 
 import (
+	"bytes"
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -2576,3 +2578,46 @@ func (et *exampleToken) expectedMatches() []string {
 	}
 	return []string{et.input}
 }
+
+var updateOverlaps = flag.Bool("update-overlaps", false, "rewrite testdata/provider_overlaps.golden from the current providers")
+
+// TestProviderOverlapsGolden runs every example through the full database and
+// compares the matrix of providers firing on each other's examples with the
+// golden file, so a pattern change that starts or stops an overlap shows up in
+// review. Regenerate it with -update-overlaps.
+func TestProviderOverlapsGolden(t *testing.T) {
+	t.Parallel()
+	scanner, err := NewScanner(prodConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	examples := make(map[string][]string, len(exampleTokens))
+	for providerName, tokens := range exampleTokens {
+		for _, example := range tokens {
+			if !example.shouldNotMatch {
+				examples[providerName] = append(examples[providerName], example.input)
+			}
+		}
+	}
+	report, err := AnalyzeOverlaps(context.Background(), scanner, examples, OverlapOptions{})
+	require.NoError(t, err)
+	var got bytes.Buffer
+	_, err = report.WriteTo(&got)
+	require.NoError(t, err)
+
+	const golden = "testdata/provider_overlaps.golden"
+	if *updateOverlaps {
+		require.NoError(t, os.WriteFile(golden, got.Bytes(), 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err, "generate the golden file with -update-overlaps")
+	require.Equal(t, string(want), got.String(), "provider overlaps changed; review the diff and regenerate with -update-overlaps")
+	for _, overlap := range report.Unexpected() {
+		t.Errorf("%s fires on %d/%d examples of %s; declare the overlap in config/overlaps.yml or fix the patterns",
+			overlap.FiredBy, overlap.Examples, overlap.Total, overlap.Provider)
+	}
+	for _, declared := range report.Unobserved(config.LoadDefaultExpectedOverlaps()) {
+		t.Errorf("%s and %s are declared to overlap in config/overlaps.yml but fire on none of each other's examples; remove the declaration",
+			declared.Providers[0], declared.Providers[1])
+	}
+}
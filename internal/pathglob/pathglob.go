@@ -0,0 +1,57 @@
+// Package pathglob matches slash separated paths against glob patterns in
+// which "**" matches any number of path segments, as in .gitignore files.
+package pathglob
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches pattern. Each segment of pattern is
+// matched with path.Match against the corresponding segment of name, except
+// "**", which matches zero or more whole segments. Malformed patterns match
+// nothing.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate reports whether pattern is well formed, so configuration errors are
+// caught when loaded rather than silently matching nothing.
+func Validate(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty glob")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(rest, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
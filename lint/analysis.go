@@ -0,0 +1,428 @@
+package lint
+
+import (
+	"math"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// The estimates below work on the parsed pattern and treat it as matching
+// bytes: runes above 0xff count as a single byte value. They are estimates
+// for ranking and warning, not exact properties of the pattern.
+
+// minimum returns the length of the shortest match of re and the
+// specificity, in bits, of its loosest shortest match.
+func minimum(re *syntax.Regexp) (length int, bits float64) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && unicode.IsLetter(r) {
+				bits += 7
+			} else {
+				bits += 8
+			}
+		}
+		return len(re.Rune), bits
+	case syntax.OpCharClass:
+		return 1, math.Log2(256 / float64(classBytes(re.Rune).count()))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1, 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return minimum(re.Sub[0])
+	case syntax.OpRepeat:
+		length, bits = minimum(re.Sub[0])
+		return length * re.Min, bits * float64(re.Min)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			n, b := minimum(sub)
+			length += n
+			bits += b
+		}
+		return length, bits
+	case syntax.OpAlternate:
+		length, bits = minimum(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			n, b := minimum(sub)
+			length = min(length, n)
+			bits = math.Min(bits, b)
+		}
+		return length, bits
+	}
+	// Empty matches, optional parts, anchors and boundaries.
+	return 0, 0
+}
+
+// maximum returns the length of the longest match of re, or -1 if matches
+// can be arbitrarily long.
+func maximum(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpQuest:
+		return maximum(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus:
+		return -1
+	case syntax.OpRepeat:
+		n := maximum(re.Sub[0])
+		if re.Max < 0 || n < 0 {
+			return -1
+		}
+		return n * re.Max
+	case syntax.OpConcat, syntax.OpAlternate:
+		total := 0
+		for _, sub := range re.Sub {
+			n := maximum(sub)
+			if n < 0 {
+				return -1
+			}
+			if re.Op == syntax.OpConcat {
+				total += n
+			} else {
+				total = max(total, n)
+			}
+		}
+		return total
+	}
+	return 0
+}
+
+// longestLiteral returns the longest literal every match of re contains.
+func longestLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return longestLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return longestLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		longest := ""
+		for _, sub := range re.Sub {
+			if literal := longestLiteral(sub); len(literal) > len(longest) {
+				longest = literal
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// undelimitedStart reports whether re starts with a repetition of word
+// characters with no anchor or word boundary before it, so it can start
+// matching in the middle of a longer token.
+func undelimitedStart(re *syntax.Regexp) bool {
+	first := edge(re, true)
+	if !repeats(first) {
+		return false
+	}
+	switch body := first.Sub[0]; body.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return true
+	case syntax.OpCharClass:
+		class := classBytes(body.Rune)
+		for c := 0; c < 0x80; c++ {
+			if class.has(byte(c)) && syntax.IsWordChar(rune(c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// edge returns the first, or last, element of re.
+func edge(re *syntax.Regexp, first bool) *syntax.Regexp {
+	for {
+		switch {
+		case re.Op == syntax.OpCapture:
+			re = re.Sub[0]
+		case re.Op == syntax.OpConcat && first:
+			re = re.Sub[0]
+		case re.Op == syntax.OpConcat:
+			re = re.Sub[len(re.Sub)-1]
+		default:
+			return re
+		}
+	}
+}
+
+// repeats reports whether re matches its operand more than once.
+func repeats(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		return re.Max < 0 || re.Max > 1
+	}
+	return false
+}
+
+func unbounded(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max < 0)
+}
+
+// nestedRepeats returns the repetitions in re that repeat an unbounded
+// repetition ambiguously: where one iteration can end with a character the
+// next can start with, so the input can be split between iterations in
+// exponentially many ways.
+func nestedRepeats(re *syntax.Regexp) []string {
+	var found []string
+	if repeats(re) {
+		body := re.Sub[0]
+		first, _ := firstBytes(body)
+		last, _ := lastBytes(body)
+		if containsUnbounded(body) && first.intersects(last) {
+			return []string{re.String()}
+		}
+	}
+	for _, sub := range re.Sub {
+		found = append(found, nestedRepeats(sub)...)
+	}
+	return found
+}
+
+func containsUnbounded(re *syntax.Regexp) bool {
+	if unbounded(re) {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if containsUnbounded(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlappingAlternations returns the alternations within repetitions whose
+// alternatives can start with the same character.
+func overlappingAlternations(re *syntax.Regexp, inRepeat bool) []string {
+	if re.Op == syntax.OpAlternate && inRepeat {
+		for i, a := range re.Sub {
+			first, _ := firstBytes(a)
+			for _, b := range re.Sub[i+1:] {
+				other, _ := firstBytes(b)
+				if first.intersects(other) {
+					return []string{re.String()}
+				}
+			}
+		}
+	}
+	var found []string
+	for _, sub := range re.Sub {
+		found = append(found, overlappingAlternations(sub, inRepeat || repeats(re))...)
+	}
+	return found
+}
+
+// unnecessaryCaseInsensitivity reports whether pattern starts with a (?i)
+// flag that changes nothing: it has no letters outside character classes
+// that already hold both cases. Flags scoped to a group are not checked.
+func unnecessaryCaseInsensitivity(pattern string) bool {
+	stripped, ok := strings.CutPrefix(pattern, "(?i)")
+	if !ok {
+		return false
+	}
+	re, err := syntax.Parse(stripped, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return caseless(re)
+}
+
+func caseless(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if r < 0x80 && unicode.IsLetter(r) {
+				return false
+			}
+		}
+	case syntax.OpCharClass:
+		class := classBytes(re.Rune)
+		for c := 'A'; c <= 'Z'; c++ {
+			if class.has(byte(c)) != class.has(byte(unicode.ToLower(c))) {
+				return false
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		if !caseless(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// example returns a shortest match of re, choosing alphanumeric characters
+// from classes where it can.
+func example(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCharClass:
+		return string(classExample(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return "x"
+	case syntax.OpCapture, syntax.OpPlus:
+		return example(re.Sub[0])
+	case syntax.OpRepeat:
+		return strings.Repeat(example(re.Sub[0]), re.Min)
+	case syntax.OpConcat:
+		var b strings.Builder
+		for _, sub := range re.Sub {
+			b.WriteString(example(sub))
+		}
+		return b.String()
+	case syntax.OpAlternate:
+		shortest := example(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			if s := example(sub); len(s) < len(shortest) {
+				shortest = s
+			}
+		}
+		return shortest
+	}
+	return ""
+}
+
+func classExample(ranges []rune) rune {
+	for i := 0; i < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1] && r < 0x80; r++ {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+		}
+	}
+	return ranges[0]
+}
+
+// byteSet is a set of byte values.
+type byteSet [4]uint64
+
+func (s *byteSet) add(b byte) {
+	s[b/64] |= 1 << (b % 64)
+}
+
+func (s *byteSet) addRune(r rune, fold bool) {
+	if r > 0xff {
+		r = 0xff
+	}
+	s.add(byte(r))
+	if fold && r < 0x80 {
+		s.add(byte(unicode.ToLower(r)))
+		s.add(byte(unicode.ToUpper(r)))
+	}
+}
+
+func (s byteSet) has(b byte) bool {
+	return s[b/64]&(1<<(b%64)) != 0
+}
+
+func (s byteSet) count() int {
+	n := 0
+	for b := 0; b < 256; b++ {
+		if s.has(byte(b)) {
+			n++
+		}
+	}
+	// A class of only runes above 0xff still matches something.
+	return max(n, 1)
+}
+
+func (s byteSet) intersects(other byteSet) bool {
+	for i := range s {
+		if s[i]&other[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *byteSet) union(other byteSet) {
+	for i := range s {
+		s[i] |= other[i]
+	}
+}
+
+// classBytes returns the bytes matched by a character class's ranges.
+func classBytes(ranges []rune) byteSet {
+	var s byteSet
+	for i := 0; i < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+			if r > 0xff {
+				s.add(0xff)
+				break
+			}
+			s.add(byte(r))
+		}
+	}
+	return s
+}
+
+func anyBytes() byteSet {
+	return byteSet{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}
+}
+
+// firstBytes returns the bytes a match of re can start with, and whether re
+// can match the empty string.
+func firstBytes(re *syntax.Regexp) (byteSet, bool) {
+	return edgeBytes(re, true)
+}
+
+// lastBytes returns the bytes a match of re can end with, and whether re can
+// match the empty string.
+func lastBytes(re *syntax.Regexp) (byteSet, bool) {
+	return edgeBytes(re, false)
+}
+
+func edgeBytes(re *syntax.Regexp, first bool) (byteSet, bool) {
+	var s byteSet
+	switch re.Op {
+	case syntax.OpLiteral:
+		r := re.Rune[len(re.Rune)-1]
+		if first {
+			r = re.Rune[0]
+		}
+		s.addRune(r, re.Flags&syntax.FoldCase != 0)
+		return s, false
+	case syntax.OpCharClass:
+		return classBytes(re.Rune), false
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return anyBytes(), false
+	case syntax.OpCapture, syntax.OpPlus:
+		return edgeBytes(re.Sub[0], first)
+	case syntax.OpStar, syntax.OpQuest:
+		s, _ = edgeBytes(re.Sub[0], first)
+		return s, true
+	case syntax.OpRepeat:
+		s, empty := edgeBytes(re.Sub[0], first)
+		return s, empty || re.Min == 0
+	case syntax.OpConcat:
+		subs := re.Sub
+		for i := range subs {
+			sub := subs[i]
+			if !first {
+				sub = subs[len(subs)-1-i]
+			}
+			edge, empty := edgeBytes(sub, first)
+			s.union(edge)
+			if !empty {
+				return s, false
+			}
+		}
+		return s, true
+	case syntax.OpAlternate:
+		nullable := false
+		for _, sub := range re.Sub {
+			edge, empty := edgeBytes(sub, first)
+			s.union(edge)
+			nullable = nullable || empty
+		}
+		return s, nullable
+	}
+	return s, true
+}
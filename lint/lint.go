@@ -0,0 +1,287 @@
+// Package lint checks provider patterns for mistakes that make them slow,
+// noisy or impossible to compile, before they reach the shipped
+// configuration.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+// DefaultMinSpecificity is the fewest bits of specificity a pattern may have
+// before it is reported as too broad. A pattern with n bits matches a random
+// string of its minimum length with probability 2^-n; 48 bits is roughly a
+// run of 8 random alphanumeric characters plus a 5 character literal prefix.
+const DefaultMinSpecificity = 48
+
+// Severity is how serious an issue is.
+type Severity string
+
+const (
+	// SeverityError issues make the configuration unusable or a pattern
+	// match nothing useful. They always fail validation.
+	SeverityError Severity = "error"
+	// SeverityWarning issues make a pattern noisy or expensive. They fail
+	// strict validation.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo issues note what the linter could not check.
+	SeverityInfo Severity = "info"
+)
+
+// The checks run by Lint.
+const (
+	CheckCompile         = "compile"
+	CheckSyntax          = "syntax"
+	CheckEmptyMatch      = "empty-match"
+	CheckBroad           = "broad"
+	CheckWordBoundary    = "word-boundary"
+	CheckNestedRepeat    = "nested-repeat"
+	CheckAlternation     = "overlapping-alternation"
+	CheckCaseInsensitive = "case-insensitive"
+	CheckDuplicate       = "duplicate"
+	CheckOverlap         = "overlap"
+)
+
+// Issue is a problem found with a provider's pattern.
+type Issue struct {
+	Provider string   `json:"provider"`
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Suggestion says how to fix the pattern.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// String formats the issue as a single line followed by its suggestion.
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s: %s: %s [%s]", i.Provider, i.Severity, i.Message, i.Check)
+	if i.Suggestion != "" {
+		s += "\n    " + i.Suggestion
+	}
+	return s
+}
+
+// PatternStats estimates how broadly a pattern matches.
+type PatternStats struct {
+	Provider string `json:"provider"`
+	// Analyzed is false if the pattern uses syntax the linter cannot parse;
+	// the other fields are then zero.
+	Analyzed bool `json:"analyzed"`
+	// MinLength and MaxLength bound the length of a match in characters.
+	// MaxLength is -1 if matches can be arbitrarily long.
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length"`
+	// Specificity is the number of bits a match of the minimum length
+	// carries: the pattern matches a random byte string of that length with
+	// probability 2^-Specificity.
+	Specificity float64 `json:"specificity"`
+	// Literal is the longest literal every match contains.
+	Literal string `json:"literal,omitempty"`
+}
+
+// Report is the result of linting a set of providers.
+type Report struct {
+	Issues   []Issue        `json:"issues"`
+	Patterns []PatternStats `json:"patterns"`
+}
+
+// Count returns the number of issues of the given severity.
+func (r *Report) Count(severity Severity) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether the report fails validation: if it has errors or,
+// when strict, warnings.
+func (r *Report) Failed(strict bool) bool {
+	return r.Count(SeverityError) > 0 || (strict && r.Count(SeverityWarning) > 0)
+}
+
+// Options configures Lint. Zero fields take the defaults noted.
+type Options struct {
+	// MinSpecificity defaults to DefaultMinSpecificity.
+	MinSpecificity float64
+	// SkipCompile skips compiling each pattern into its own Hyperscan
+	// database, which is the slowest check.
+	SkipCompile bool
+	// Expected and Precedence declare the pairs of providers known to
+	// overlap, which are not reported as overlapping: those listed in the
+	// expected overlaps, and those with a declared precedence relation, whose
+	// overlapping findings are resolved when scanning. Nil declares none.
+	Expected   *config.ExpectedOverlaps
+	Precedence *config.Precedence
+}
+
+// pattern is a provider with its parsed pattern.
+type pattern struct {
+	provider *config.ProviderConfig
+	// re and matcher are nil if the pattern could not be parsed.
+	re      *syntax.Regexp
+	matcher *regexp.Regexp
+	stats   PatternStats
+}
+
+// Lint checks every provider's pattern, and every pair of patterns for
+// overlap. Issues are sorted by provider, then check.
+func Lint(providers []*config.ProviderConfig, opts Options) *Report {
+	if opts.MinSpecificity <= 0 {
+		opts.MinSpecificity = DefaultMinSpecificity
+	}
+	report := &Report{Issues: []Issue{}}
+	patterns := make([]*pattern, len(providers))
+	for i, provider := range providers {
+		p := &pattern{provider: provider, stats: PatternStats{Provider: provider.Name}}
+		patterns[i] = p
+		if !opts.SkipCompile {
+			if err := compile(provider); err != nil {
+				report.add(p, CheckCompile, SeverityError, fmt.Sprintf("does not compile: %v", err),
+					"fix the pattern so Hyperscan accepts it; the whole provider database fails to compile otherwise")
+			}
+		}
+		re, err := syntax.Parse(provider.Pattern, syntax.Perl)
+		if err != nil {
+			report.add(p, CheckSyntax, SeverityInfo, fmt.Sprintf("not analyzed: %v", err), "")
+			report.Patterns = append(report.Patterns, p.stats)
+			continue
+		}
+		p.re = re
+		p.matcher, _ = regexp.Compile(`^(?:` + provider.Pattern + `)$`)
+		p.stats.Analyzed = true
+		p.stats.MinLength, p.stats.Specificity = minimum(p.re)
+		p.stats.MaxLength = maximum(p.re)
+		p.stats.Literal = longestLiteral(p.re)
+		report.Patterns = append(report.Patterns, p.stats)
+		lintPattern(report, p, opts)
+	}
+	lintOverlaps(report, patterns, opts)
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Check < b.Check
+	})
+	return report
+}
+
+// LintConfig lints every provider of cfg.
+func LintConfig(cfg *config.Config, opts Options) *Report {
+	return Lint(cfg.HyperscanProviders(), opts)
+}
+
+// declaredOverlap reports whether opts declares that providers a and b
+// overlap.
+func declaredOverlap(a, b string, opts Options) bool {
+	return opts.Expected.Lookup(a, b) != nil || opts.Precedence.Relation(a, b) != config.RelationNone
+}
+
+func (r *Report) add(p *pattern, check string, severity Severity, message, suggestion string) {
+	r.Issues = append(r.Issues, Issue{
+		Provider:   p.provider.Name,
+		Check:      check,
+		Severity:   severity,
+		Message:    message,
+		Suggestion: suggestion,
+	})
+}
+
+// compile builds a database holding only provider, so a compile failure is
+// attributed to the right pattern.
+func compile(provider *config.ProviderConfig) error {
+	cfg, err := config.LoadCustomConfig([]*config.ProviderConfig{provider})
+	if err != nil {
+		return err
+	}
+	db, err := cfg.Database()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func lintPattern(report *Report, p *pattern, opts Options) {
+	stats := p.stats
+	if stats.MinLength == 0 {
+		report.add(p, CheckEmptyMatch, SeverityError, "matches the empty string",
+			"make the repeated parts of the pattern require at least one character, for example + or {1,} instead of *")
+		// Every other estimate is meaningless for such a pattern.
+		return
+	}
+	if stats.Specificity < opts.MinSpecificity {
+		report.add(p, CheckBroad, SeverityWarning,
+			fmt.Sprintf("matches too broadly: %.0f bits of specificity over at least %d characters, below %.0f", stats.Specificity, stats.MinLength, opts.MinSpecificity),
+			"anchor the pattern on the token's literal prefix, or require more characters from narrower classes")
+	}
+	if undelimitedStart(p.re) {
+		report.add(p, CheckWordBoundary, SeverityWarning,
+			"starts with repeated word characters and no word boundary, so it matches inside longer tokens",
+			`start the pattern with \b, a literal prefix, or the surrounding context such as a key name`)
+	}
+	for _, problem := range nestedRepeats(p.re) {
+		report.add(p, CheckNestedRepeat, SeverityWarning,
+			"repeats an unbounded repetition: "+problem,
+			"flatten the repetition into a single quantifier over one character class; nested quantifiers blow up matching state")
+	}
+	for _, problem := range overlappingAlternations(p.re, false) {
+		report.add(p, CheckAlternation, SeverityWarning,
+			"repeats alternatives that can start with the same character: "+problem,
+			"merge the alternatives into one character class or give them distinct first characters")
+	}
+	if unnecessaryCaseInsensitivity(p.provider.Pattern) {
+		report.add(p, CheckCaseInsensitive, SeverityWarning,
+			"is case-insensitive, but no part of it depends on case",
+			"remove the (?i) flag")
+	}
+}
+
+// lintOverlaps reports pairs of providers with the same pattern, and
+// providers whose shortest match is matched by another provider's pattern
+// unless opts declares the pair.
+func lintOverlaps(report *Report, patterns []*pattern, opts Options) {
+	byPattern := make(map[string]*pattern)
+	duplicates := make(map[*pattern]bool)
+	for _, p := range patterns {
+		if first, ok := byPattern[p.provider.Pattern]; ok {
+			report.add(p, CheckDuplicate, SeverityError,
+				"has the same pattern as "+first.provider.Name,
+				"remove one of the providers; every match is reported twice")
+			duplicates[p] = true
+			continue
+		}
+		byPattern[p.provider.Pattern] = p
+	}
+	for _, p := range patterns {
+		if p.re == nil || p.stats.MinLength == 0 || duplicates[p] {
+			continue
+		}
+		sample := example(p.re)
+		var others []string
+		for _, other := range patterns {
+			if other == p || other.matcher == nil || other.provider.Pattern == p.provider.Pattern {
+				continue
+			}
+			if declaredOverlap(p.provider.Name, other.provider.Name, opts) {
+				continue
+			}
+			if other.matcher.MatchString(sample) {
+				others = append(others, other.provider.Name)
+			}
+		}
+		if len(others) > 0 {
+			sort.Strings(others)
+			report.add(p, CheckOverlap, SeverityWarning,
+				fmt.Sprintf("shortest match %q is also matched by %s", sample, strings.Join(others, ", ")),
+				"give the patterns distinct literal prefixes or lengths, or confirm the overlap is intended")
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+// checks returns the checks reported for provider.
+func checks(report *Report, provider string) []string {
+	var found []string
+	for _, issue := range report.Issues {
+		if issue.Provider == provider {
+			found = append(found, issue.Check)
+		}
+	}
+	return found
+}
+
+func TestLintPatterns(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{name: "prefixed token", pattern: `ghp_[A-Za-z0-9]{36}`},
+		{name: "delimited hex", pattern: `\b[a-f0-9]{40}\b`},
+		{name: "optional everything", pattern: `(?:tok_)?[a-z]*`, want: []string{CheckEmptyMatch}},
+		{name: "short digits", pattern: `\b[0-9]{6}\b`, want: []string{CheckBroad}},
+		{name: "bare hex", pattern: `[a-f0-9]{40}`, want: []string{CheckWordBoundary}},
+		{name: "nested repeat", pattern: `key_(?:[a-z]+[0-9]?)+[A-Za-z0-9]{20}`, want: []string{CheckNestedRepeat}},
+		{name: "dotted labels", pattern: `host_(?:[a-z]+\.)+[a-z]{24}`},
+		{name: "overlapping alternation", pattern: `key_(?:[a-z]+|[a-f0-9]{2})+[0-9]{20}`, want: []string{CheckNestedRepeat, CheckAlternation}},
+		{name: "needless case folding", pattern: `(?i)[0-9a-fA-F]{32}_[0-9]{12}`, want: []string{CheckCaseInsensitive, CheckWordBoundary}},
+		{name: "needed case folding", pattern: `(?i)token_[0-9a-f]{32}`},
+		{name: "pcre only syntax", pattern: `(?<=key=)[a-z0-9]{32}`, want: []string{CheckSyntax}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			report := Lint([]*config.ProviderConfig{{Name: "TEST", Pattern: tc.pattern}}, Options{SkipCompile: true})
+			require.ElementsMatch(t, tc.want, checks(report, "TEST"))
+		})
+	}
+}
+
+func TestLintPatternStats(t *testing.T) {
+	t.Parallel()
+	report := Lint([]*config.ProviderConfig{
+		{Name: "PREFIXED", Pattern: `ghp_[A-Za-z0-9]{36}`},
+		{Name: "UNBOUNDED", Pattern: `\bsk_[a-z]{8,}\b`},
+	}, Options{SkipCompile: true})
+	require.Len(t, report.Patterns, 2)
+
+	prefixed := report.Patterns[0]
+	require.True(t, prefixed.Analyzed)
+	require.Equal(t, 40, prefixed.MinLength)
+	require.Equal(t, 40, prefixed.MaxLength)
+	require.Equal(t, "ghp_", prefixed.Literal)
+	require.InDelta(t, 32+36*2.046, prefixed.Specificity, 0.1)
+
+	unbounded := report.Patterns[1]
+	require.Equal(t, 11, unbounded.MinLength)
+	require.Equal(t, -1, unbounded.MaxLength)
+}
+
+func TestLintOverlaps(t *testing.T) {
+	t.Parallel()
+	report := Lint([]*config.ProviderConfig{
+		{Name: "NPM_TOKEN", Pattern: `\bnpm_[A-Za-z0-9]{36}\b`},
+		{Name: "NPM_TOKEN_COPY", Pattern: `\bnpm_[A-Za-z0-9]{36}\b`},
+		{Name: "NPM_TOKEN_LOOSE", Pattern: `\bnpm_[A-Za-z0-9]{30,40}\b`},
+		{Name: "PYPI_TOKEN", Pattern: `\bpypi-[A-Za-z0-9_-]{50,}\b`},
+	}, Options{SkipCompile: true})
+	require.Equal(t, []string{CheckOverlap}, checks(report, "NPM_TOKEN"))
+	require.Equal(t, []string{CheckDuplicate}, checks(report, "NPM_TOKEN_COPY"))
+	require.Empty(t, checks(report, "NPM_TOKEN_LOOSE"), "the loose pattern's shortest match is too short for the others")
+	require.Empty(t, checks(report, "PYPI_TOKEN"))
+	for _, issue := range report.Issues {
+		if issue.Check == CheckOverlap {
+			require.Contains(t, issue.Message, "NPM_TOKEN_LOOSE")
+			require.NotContains(t, issue.Message, "NPM_TOKEN_COPY", "duplicates are reported once, as duplicates")
+			require.NotEmpty(t, issue.Suggestion)
+		}
+	}
+	require.True(t, report.Failed(false))
+}
+
+func TestLintOverlapsSkipsDeclaredPairs(t *testing.T) {
+	t.Parallel()
+	providers := []*config.ProviderConfig{
+		{Name: "NPM_TOKEN", Pattern: `\bnpm_[A-Za-z0-9]{36}\b`},
+		{Name: "NPM_TOKEN_LOOSE", Pattern: `\bnpm_[A-Za-z0-9]{30,40}\b`},
+		{Name: "NPM_TOKEN_PREFIXED", Pattern: `\bnpm_[A-Z][A-Za-z0-9]{35}\b`},
+	}
+	report := Lint(providers, Options{SkipCompile: true})
+	require.Equal(t, []string{CheckOverlap}, checks(report, "NPM_TOKEN"))
+	require.Equal(t, []string{CheckOverlap}, checks(report, "NPM_TOKEN_PREFIXED"))
+
+	expected, err := config.ParseExpectedOverlaps([]byte("overlaps:\n  - providers: [NPM_TOKEN_LOOSE, NPM_TOKEN]\n    reason: r\n"))
+	require.NoError(t, err)
+	precedence, err := config.NewPrecedence([]*config.PrecedenceRule{
+		{Provider: "NPM_TOKEN_PREFIXED", Supersedes: []string{"NPM_TOKEN", "NPM_TOKEN_LOOSE"}},
+	}, providers)
+	require.NoError(t, err)
+	report = Lint(providers, Options{SkipCompile: true, Expected: expected})
+	require.Empty(t, checks(report, "NPM_TOKEN"))
+	require.Equal(t, []string{CheckOverlap}, checks(report, "NPM_TOKEN_PREFIXED"))
+	report = Lint(providers, Options{SkipCompile: true, Expected: expected, Precedence: precedence})
+	require.Empty(t, report.Issues)
+	require.False(t, report.Failed(true))
+}
+
+func TestLintCompile(t *testing.T) {
+	t.Parallel()
+	report := Lint([]*config.ProviderConfig{{Name: "BROKEN", Pattern: `tok_[a-z`}}, Options{})
+	require.Equal(t, []string{CheckCompile, CheckSyntax}, checks(report, "BROKEN"))
+	require.True(t, report.Failed(false))
+}
+
+// TestDefaultConfigLint fails when a shipped pattern has an error-level
+// issue.
+func TestDefaultConfigLint(t *testing.T) {
+	t.Parallel()
+	report := Lint(config.GetDefaultConfig(), Options{})
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError {
+			t.Error(issue)
+		}
+	}
+	require.Len(t, report.Patterns, len(config.GetDefaultConfig()))
+}
@@ -0,0 +1,309 @@
+// Package notify reports leaked secrets to the partners that issued them, so
+// they can be revoked. Reports are batched per provider, signed, and kept in
+// an on-disk queue until the partner acknowledges them.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/github/go-stats"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	// KeyIDHeader and SignatureHeader carry the signing key's ID and the
+	// base64 encoded signature of the request body.
+	KeyIDHeader     = "Hypercredscan-Key-Identifier"
+	SignatureHeader = "Hypercredscan-Signature"
+
+	// reportSource is the source given for every report.
+	reportSource = "hypercredscan"
+
+	// maxDrainBytes bounds how much of a partner's response is read.
+	maxDrainBytes = 64 << 10
+)
+
+// Stats recorded by the dispatcher, tagged by provider.
+const (
+	deliveredStat    = "hypercredscan.notify.delivered"
+	failedStat       = "hypercredscan.notify.failed"
+	deadLetteredStat = "hypercredscan.notify.dead_lettered"
+)
+
+// Options configures a Dispatcher. Zero fields take the defaults noted.
+type Options struct {
+	// Endpoints maps provider names to the URL their reports are posted to.
+	// Findings of other providers are ignored.
+	Endpoints map[string]string
+	Signer    Signer
+	// QueueDir is where undelivered batches are kept.
+	QueueDir string
+	// Client defaults to a client with a 30 second timeout.
+	Client *http.Client
+	// MaxBatch is the most reports sent in one request. Defaults to 100.
+	MaxBatch int
+	// MaxAttempts is the number of failed deliveries after which a batch is
+	// dead-lettered. Defaults to 10.
+	MaxAttempts int
+	// Backoff is the wait after the first failed delivery, doubled after each
+	// further failure up to MaxBackoff. They default to one minute and six
+	// hours.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Stats defaults to stats.NullStatter.
+	Stats stats.Client
+}
+
+// Dispatcher is a findings.Processor that reports findings to partners.
+// Findings are collected into batches per provider and configuration version
+// which are queued on disk
+// when full or on Flush, and delivered by Deliver. Flush delivers once; long
+// running callers should also call Run so failed batches are retried.
+type Dispatcher struct {
+	opts  Options
+	queue *Queue
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[batchKey][]Report
+
+	// deliverMu serializes delivery passes, so a batch is never sent twice
+	// concurrently.
+	deliverMu sync.Mutex
+}
+
+// NewDispatcher returns a Dispatcher, opening its queue. Batches left in the
+// queue by a previous run are delivered along with new ones.
+func NewDispatcher(opts Options) (*Dispatcher, error) {
+	if opts.Signer == nil {
+		return nil, errors.New("a signer is required")
+	}
+	if opts.QueueDir == "" {
+		return nil, errors.New("a queue directory is required")
+	}
+	for provider, endpoint := range opts.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("provider %s: endpoint %q is not an http or https URL", provider, endpoint)
+		}
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Minute
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 6 * time.Hour
+	}
+	if opts.Stats == nil {
+		opts.Stats = stats.NullStatter
+	}
+	queue, err := OpenQueue(opts.QueueDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{
+		opts:    opts,
+		queue:   queue,
+		now:     time.Now,
+		pending: make(map[batchKey][]Report),
+	}, nil
+}
+
+// Queue returns the dispatcher's queue, for inspecting pending and
+// dead-lettered batches.
+func (d *Dispatcher) Queue() *Queue {
+	return d.queue
+}
+
+// ProcessFinding implements findings.Processor.
+func (d *Dispatcher) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	if _, ok := d.opts.Endpoints[f.Provider]; !ok {
+		return nil
+	}
+	report := Report{
+		Token:    string(f.Secret),
+		Type:     f.Provider,
+		URL:      f.Path,
+		BlobSHA:  f.BlobSHA,
+		Source:   reportSource,
+		Detected: d.now().UTC().Format(time.RFC3339),
+	}
+	key := batchKey{provider: f.Provider, configVersion: f.ConfigVersion}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[key] = append(d.pending[key], report)
+	if len(d.pending[key]) < d.opts.MaxBatch {
+		return nil
+	}
+	return d.enqueueLocked(key)
+}
+
+// batchKey identifies the batch a finding is collected into. Findings of
+// different configuration versions are batched apart, so delivery stats can
+// be tagged with the version that found them.
+type batchKey struct {
+	provider      string
+	configVersion string
+}
+
+// Flush implements findings.Processor. It queues all partial batches and
+// makes one delivery pass; batches that fail stay queued.
+func (d *Dispatcher) Flush() error {
+	d.mu.Lock()
+	for key := range d.pending {
+		if err := d.enqueueLocked(key); err != nil {
+			d.mu.Unlock()
+			return err
+		}
+	}
+	d.mu.Unlock()
+	return d.Deliver(context.Background())
+}
+
+func (d *Dispatcher) enqueueLocked(key batchKey) error {
+	reports := d.pending[key]
+	delete(d.pending, key)
+	if len(reports) == 0 {
+		return nil
+	}
+	now := d.now()
+	return d.queue.Put(&Batch{
+		ID:            newBatchID(now),
+		Provider:      key.provider,
+		ConfigVersion: key.configVersion,
+		Endpoint:      d.opts.Endpoints[key.provider],
+		Reports:       reports,
+		NextAttempt:   now,
+	})
+}
+
+// Run delivers due batches every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Deliver(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver sends every batch that is due. Delivered batches are removed from
+// the queue; failed ones are rescheduled with exponential backoff, or
+// dead-lettered once they run out of attempts or the partner rejects them
+// outright. Deliver only returns an error if the queue itself fails.
+func (d *Dispatcher) Deliver(ctx context.Context) error {
+	d.deliverMu.Lock()
+	defer d.deliverMu.Unlock()
+	due, err := d.queue.Due(d.now())
+	if err != nil {
+		return err
+	}
+	for _, b := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := d.deliver(ctx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, b *Batch) error {
+	tags := stats.Tags{"provider": b.Provider, "config_version": b.ConfigVersion}
+	err := d.send(ctx, b)
+	if err == nil {
+		d.opts.Stats.Counter(deliveredStat, tags, int64(len(b.Reports)))
+		return d.queue.Remove(b)
+	}
+
+	d.opts.Stats.Counter(failedStat, tags, 1)
+	b.Attempts++
+	b.LastError = err.Error()
+	var permanent *permanentError
+	if errors.As(err, &permanent) || b.Attempts >= d.opts.MaxAttempts {
+		d.opts.Stats.Counter(deadLetteredStat, tags, int64(len(b.Reports)))
+		return d.queue.DeadLetter(b)
+	}
+	b.NextAttempt = d.now().Add(d.backoff(b.Attempts))
+	return d.queue.Put(b)
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.Backoff
+	for i := 1; i < attempts && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.MaxBackoff {
+		wait = d.opts.MaxBackoff
+	}
+	return wait
+}
+
+// permanentError is a delivery failure that retrying will not fix.
+type permanentError struct {
+	status int
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("endpoint rejected batch with status %d", e.status)
+}
+
+func (d *Dispatcher) send(ctx context.Context, b *Batch) error {
+	body, err := json.Marshal(b.Reports)
+	if err != nil {
+		return err
+	}
+	signature, err := d.opts.Signer.Sign(body)
+	if err != nil {
+		return fmt.Errorf("signing batch: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(KeyIDHeader, d.opts.Signer.KeyID())
+	req.Header.Set(SignatureHeader, base64.StdEncoding.EncodeToString(signature))
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	default:
+		return &permanentError{status: resp.StatusCode}
+	}
+}
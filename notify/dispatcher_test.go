@@ -0,0 +1,320 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/go-stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// partner is a local revocation endpoint that checks signatures and can be
+// told to fail or slow down.
+type partner struct {
+	t   *testing.T
+	key *ecdsa.PublicKey
+
+	mu       sync.Mutex
+	statuses []int
+	delay    time.Duration
+	requests int
+	batches  [][]Report
+}
+
+func (p *partner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests++
+	delay := p.delay
+	status := http.StatusOK
+	if len(p.statuses) > 0 {
+		status, p.statuses = p.statuses[0], p.statuses[1:]
+	}
+	p.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	// The handler runs on the server's goroutine, where require must not be
+	// used; failures are recorded with assert and the request rejected.
+	body, err := io.ReadAll(r.Body)
+	signature, sigErr := base64.StdEncoding.DecodeString(r.Header.Get(SignatureHeader))
+	if !assert.NoError(p.t, err) ||
+		!assert.Equal(p.t, "test-key", r.Header.Get(KeyIDHeader)) ||
+		!assert.NoError(p.t, sigErr) ||
+		!assert.True(p.t, Verify(p.key, body, signature), "signature does not verify") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	var reports []Report
+	if !assert.NoError(p.t, json.Unmarshal(body, &reports)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	p.batches = append(p.batches, reports)
+	p.mu.Unlock()
+}
+
+func (p *partner) fail(statuses ...int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses = append(p.statuses, statuses...)
+}
+
+func (p *partner) received() (requests int, batches [][]Report) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests, append([][]Report(nil), p.batches...)
+}
+
+type dispatcherFixture struct {
+	partner    *partner
+	server     *httptest.Server
+	dispatcher *Dispatcher
+	now        time.Time
+}
+
+func newDispatcherFixture(t *testing.T, opts Options) *dispatcherFixture {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	fx := &dispatcherFixture{
+		partner: &partner{t: t, key: &key.PublicKey},
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	fx.server = httptest.NewServer(fx.partner)
+	t.Cleanup(fx.server.Close)
+
+	opts.Signer = NewECDSASigner("test-key", key)
+	if opts.QueueDir == "" {
+		opts.QueueDir = t.TempDir()
+	}
+	opts.Endpoints = map[string]string{
+		"DOPPLER_SERVICE_TOKEN": fx.server.URL + "/doppler",
+		"PYPI_API_TOKEN":        fx.server.URL + "/pypi",
+	}
+	fx.dispatcher, err = NewDispatcher(opts)
+	require.NoError(t, err)
+	fx.dispatcher.now = func() time.Time { return fx.now }
+	return fx
+}
+
+func (fx *dispatcherFixture) process(t *testing.T, provider, secret string) {
+	t.Helper()
+	f := &findings.Finding{Provider: provider, Secret: []byte(secret), Path: "app/config.yml", BlobSHA: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"}
+	require.NoError(t, fx.dispatcher.ProcessFinding(context.Background(), f))
+}
+
+func (fx *dispatcherFixture) pending(t *testing.T) []*Batch {
+	t.Helper()
+	pending, err := fx.dispatcher.Queue().Pending()
+	require.NoError(t, err)
+	return pending
+}
+
+func TestDispatcherBatchesPerProvider(t *testing.T) {
+	t.Parallel()
+	fx := newDispatcherFixture(t, Options{MaxBatch: 2})
+	for _, secret := range []string{"dp.st.a", "dp.st.b", "dp.st.c", "dp.st.d", "dp.st.e"} {
+		fx.process(t, "DOPPLER_SERVICE_TOKEN", secret)
+	}
+	fx.process(t, "PYPI_API_TOKEN", "pypi-a")
+	fx.process(t, "GITHUB_PERSONAL_ACCESS_TOKEN", "ghp_not_a_partner")
+
+	// Full batches are queued as they fill, but nothing is sent before Flush.
+	require.Len(t, fx.pending(t), 2)
+	requests, _ := fx.partner.received()
+	require.Zero(t, requests)
+
+	require.NoError(t, fx.dispatcher.Flush())
+	require.Empty(t, fx.pending(t))
+	_, batches := fx.partner.received()
+	require.Len(t, batches, 4)
+
+	sizes := map[string][]int{}
+	for _, batch := range batches {
+		sizes[batch[0].Type] = append(sizes[batch[0].Type], len(batch))
+	}
+	require.Equal(t, map[string][]int{"DOPPLER_SERVICE_TOKEN": {2, 2, 1}, "PYPI_API_TOKEN": {1}}, sizes)
+	require.Equal(t, Report{
+		Token:    "dp.st.a",
+		Type:     "DOPPLER_SERVICE_TOKEN",
+		URL:      "app/config.yml",
+		BlobSHA:  "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Source:   "hypercredscan",
+		Detected: "2024-01-01T00:00:00Z",
+	}, batches[0][0])
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	t.Parallel()
+	fx := newDispatcherFixture(t, Options{Backoff: time.Minute, MaxBackoff: time.Hour})
+	fx.partner.fail(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	fx.process(t, "PYPI_API_TOKEN", "pypi-a")
+
+	require.NoError(t, fx.dispatcher.Flush())
+	pending := fx.pending(t)
+	require.Len(t, pending, 1)
+	require.Equal(t, 1, pending[0].Attempts)
+	require.Equal(t, fx.now.Add(time.Minute), pending[0].NextAttempt)
+	require.Contains(t, pending[0].LastError, "status 503")
+
+	// Not due yet.
+	require.NoError(t, fx.dispatcher.Deliver(context.Background()))
+	requests, _ := fx.partner.received()
+	require.Equal(t, 1, requests)
+
+	fx.now = fx.now.Add(time.Minute)
+	require.NoError(t, fx.dispatcher.Deliver(context.Background()))
+	pending = fx.pending(t)
+	require.Len(t, pending, 1)
+	require.Equal(t, 2, pending[0].Attempts)
+	require.Equal(t, fx.now.Add(2*time.Minute), pending[0].NextAttempt)
+
+	fx.now = fx.now.Add(2 * time.Minute)
+	require.NoError(t, fx.dispatcher.Deliver(context.Background()))
+	require.Empty(t, fx.pending(t))
+	requests, batches := fx.partner.received()
+	require.Equal(t, 3, requests)
+	require.Len(t, batches, 1)
+}
+
+func TestDispatcherSlowEndpoint(t *testing.T) {
+	t.Parallel()
+	fx := newDispatcherFixture(t, Options{Client: &http.Client{Timeout: 20 * time.Millisecond}})
+	fx.partner.mu.Lock()
+	fx.partner.delay = 200 * time.Millisecond
+	fx.partner.mu.Unlock()
+	fx.process(t, "DOPPLER_SERVICE_TOKEN", "dp.st.a")
+
+	require.NoError(t, fx.dispatcher.Flush())
+	pending := fx.pending(t)
+	require.Len(t, pending, 1)
+	require.Equal(t, 1, pending[0].Attempts)
+}
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	t.Parallel()
+	fx := newDispatcherFixture(t, Options{MaxAttempts: 2, Backoff: time.Minute})
+	fx.partner.fail(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadRequest)
+	fx.process(t, "DOPPLER_SERVICE_TOKEN", "dp.st.a")
+	require.NoError(t, fx.dispatcher.Flush())
+	fx.now = fx.now.Add(time.Minute)
+	require.NoError(t, fx.dispatcher.Deliver(context.Background()))
+	require.Empty(t, fx.pending(t))
+
+	// A rejected batch is dead-lettered without retrying.
+	fx.process(t, "PYPI_API_TOKEN", "pypi-a")
+	require.NoError(t, fx.dispatcher.Flush())
+	require.Empty(t, fx.pending(t))
+
+	dead, err := fx.dispatcher.Queue().DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, dead, 2)
+	require.Equal(t, "DOPPLER_SERVICE_TOKEN", dead[0].Provider)
+	require.Equal(t, 2, dead[0].Attempts)
+	require.Equal(t, "PYPI_API_TOKEN", dead[1].Provider)
+	require.Equal(t, 1, dead[1].Attempts)
+	require.Contains(t, dead[1].LastError, "status 400")
+}
+
+func TestDispatcherResumesQueue(t *testing.T) {
+	t.Parallel()
+	first := newDispatcherFixture(t, Options{})
+	first.partner.fail(http.StatusBadGateway)
+	first.process(t, "PYPI_API_TOKEN", "pypi-a")
+	require.NoError(t, first.dispatcher.Flush())
+	require.Len(t, first.pending(t), 1)
+
+	// A new dispatcher, say after a restart, picks the batch up once due.
+	second, err := NewDispatcher(first.dispatcher.opts)
+	require.NoError(t, err)
+	second.now = func() time.Time { return first.now.Add(time.Hour) }
+	require.NoError(t, second.Deliver(context.Background()))
+	pending, err := second.Queue().Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	_, batches := first.partner.received()
+	require.Len(t, batches, 1)
+	require.Equal(t, "pypi-a", batches[0][0].Token)
+}
+
+func TestDispatcherBackoffIsCapped(t *testing.T) {
+	t.Parallel()
+	d := &Dispatcher{opts: Options{Backoff: time.Minute, MaxBackoff: 5 * time.Minute}}
+	var waits []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		waits = append(waits, d.backoff(attempts))
+	}
+	require.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}, waits)
+}
+
+func TestNewDispatcherRejectsBadOptions(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer := NewECDSASigner("k", key)
+
+	_, err = NewDispatcher(Options{QueueDir: t.TempDir()})
+	require.Error(t, err)
+	_, err = NewDispatcher(Options{Signer: signer})
+	require.Error(t, err)
+	_, err = NewDispatcher(Options{Signer: signer, QueueDir: t.TempDir(), Endpoints: map[string]string{"PYPI_API_TOKEN": "pypi.example"}})
+	require.ErrorContains(t, err, "PYPI_API_TOKEN")
+}
+
+// taggingStatter records the tags of every counter increment.
+type taggingStatter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (s *taggingStatter) Counter(name string, tags stats.Tags, value int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[name+"/"+tags["provider"]+"/"+tags["config_version"]] += value
+}
+
+func TestDispatcherBatchesPerConfigVersion(t *testing.T) {
+	t.Parallel()
+	statter := &taggingStatter{counts: make(map[string]int64)}
+	fx := newDispatcherFixture(t, Options{Stats: statter})
+	for _, f := range []*findings.Finding{
+		{Provider: "PYPI_API_TOKEN", Secret: []byte("pypi-a"), ConfigVersion: "5d41402abc4b2a76"},
+		{Provider: "PYPI_API_TOKEN", Secret: []byte("pypi-b"), ConfigVersion: "7d793037a0760186"},
+		{Provider: "PYPI_API_TOKEN", Secret: []byte("pypi-c"), ConfigVersion: "5d41402abc4b2a76"},
+	} {
+		require.NoError(t, fx.dispatcher.ProcessFinding(context.Background(), f))
+	}
+
+	// Findings from before and after a reload are delivered apart, so each
+	// delivery is counted against the configuration that found it.
+	require.NoError(t, fx.dispatcher.Flush())
+	_, batches := fx.partner.received()
+	require.Len(t, batches, 2)
+	require.Equal(t, map[string]int64{
+		deliveredStat + "/PYPI_API_TOKEN/5d41402abc4b2a76": 2,
+		deliveredStat + "/PYPI_API_TOKEN/7d793037a0760186": 1,
+	}, statter.counts)
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// deadLetterDir is the subdirectory of a queue holding batches that will not
+// be retried.
+const deadLetterDir = "dead"
+
+// Report is a single leaked secret as sent to a partner.
+type Report struct {
+	Token    string `json:"token"`
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`
+	BlobSHA  string `json:"blob_sha,omitempty"`
+	Source   string `json:"source"`
+	Detected string `json:"detected_at"`
+}
+
+// Batch is a set of reports for one provider awaiting delivery to one
+// endpoint, along with its delivery history. ConfigVersion is the version of
+// the provider configuration the reported secrets were found with.
+type Batch struct {
+	ID            string    `json:"id"`
+	Provider      string    `json:"provider"`
+	ConfigVersion string    `json:"config_version,omitempty"`
+	Endpoint      string    `json:"endpoint"`
+	Reports       []Report  `json:"reports"`
+	Attempts      int       `json:"attempts"`
+	NextAttempt   time.Time `json:"next_attempt"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// Queue stores undelivered batches as one JSON file each in a directory, so
+// they survive restarts. Batches hold raw secrets, so the directory and files
+// are only accessible to their owner. A Queue is safe for concurrent use by a
+// single process.
+type Queue struct {
+	dir string
+}
+
+// OpenQueue opens the queue in dir, creating it if necessary.
+func OpenQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Join(dir, deadLetterDir), 0o700); err != nil {
+		return nil, fmt.Errorf("creating queue: %w", err)
+	}
+	return &Queue{dir: dir}, nil
+}
+
+// batchSeq orders batches created within the same clock tick.
+var batchSeq atomic.Uint64
+
+// newBatchID returns an ID that sorts batches by creation time. The random
+// suffix keeps IDs from separate processes sharing a queue apart.
+func newBatchID(now time.Time) string {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("%020d-%010d-%s", now.UnixNano(), batchSeq.Add(1), hex.EncodeToString(suffix[:]))
+}
+
+// Put stores b, replacing any stored batch with the same ID. The batch is
+// written to a temporary file and renamed into place, so a crash never leaves
+// a partial batch behind.
+func (q *Queue) Put(b *Batch) error {
+	return writeBatch(q.dir, b)
+}
+
+// Remove deletes b from the queue.
+func (q *Queue) Remove(b *Batch) error {
+	err := os.Remove(batchPath(q.dir, b))
+	if errors.Is(err, iofs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// DeadLetter moves b out of the queue into the dead letter directory, where
+// it is kept for inspection but never retried.
+func (q *Queue) DeadLetter(b *Batch) error {
+	if err := writeBatch(filepath.Join(q.dir, deadLetterDir), b); err != nil {
+		return err
+	}
+	return q.Remove(b)
+}
+
+// Pending returns the batches awaiting delivery, oldest first.
+func (q *Queue) Pending() ([]*Batch, error) {
+	return readBatches(q.dir)
+}
+
+// DeadLetters returns the batches that were given up on, oldest first.
+func (q *Queue) DeadLetters() ([]*Batch, error) {
+	return readBatches(filepath.Join(q.dir, deadLetterDir))
+}
+
+// Due returns the pending batches whose next attempt is no later than now,
+// oldest first.
+func (q *Queue) Due(now time.Time) ([]*Batch, error) {
+	pending, err := q.Pending()
+	if err != nil {
+		return nil, err
+	}
+	due := pending[:0]
+	for _, b := range pending {
+		if !b.NextAttempt.After(now) {
+			due = append(due, b)
+		}
+	}
+	return due, nil
+}
+
+func batchPath(dir string, b *Batch) string {
+	return filepath.Join(dir, b.ID+".json")
+}
+
+func writeBatch(dir string, b *Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".batch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), batchPath(dir, b))
+}
+
+func readBatches(dir string) ([]*Batch, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var batches []*Batch
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, iofs.ErrNotExist) {
+			// Delivered or dead-lettered since the directory was read.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var b Batch
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("reading batch %s: %w", entry.Name(), err)
+		}
+		batches = append(batches, &b)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].ID < batches[j].ID
+	})
+	return batches, nil
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "queue")
+	q, err := OpenQueue(dir)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	early := &Batch{ID: newBatchID(now), Provider: "PYPI_API_TOKEN", Reports: []Report{{Token: "pypi-a"}}, NextAttempt: now}
+	late := &Batch{ID: newBatchID(now.Add(time.Second)), Provider: "PYPI_API_TOKEN", NextAttempt: now.Add(time.Hour)}
+	require.NoError(t, q.Put(late))
+	require.NoError(t, q.Put(early))
+
+	pending, err := q.Pending()
+	require.NoError(t, err)
+	require.Equal(t, []*Batch{early, late}, pending)
+
+	due, err := q.Due(now)
+	require.NoError(t, err)
+	require.Equal(t, []*Batch{early}, due)
+
+	// Batches hold secrets, so only their owner may read them.
+	info, err := os.Stat(filepath.Join(dir, early.ID+".json"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.NoError(t, q.DeadLetter(early))
+	require.NoError(t, q.Remove(late))
+	require.NoError(t, q.Remove(late))
+	pending, err = q.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	dead, err := q.DeadLetters()
+	require.NoError(t, err)
+	require.Equal(t, []*Batch{early}, dead)
+
+	// Reopening keeps what was stored.
+	q, err = OpenQueue(dir)
+	require.NoError(t, err)
+	dead, err = q.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+}
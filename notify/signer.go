@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Signer signs report payloads so partners can verify they came from us.
+type Signer interface {
+	// KeyID identifies the key, so partners can pick the public key to verify
+	// with while keys are rotated.
+	KeyID() string
+	Sign(payload []byte) ([]byte, error)
+}
+
+// ECDSASigner signs the SHA-256 digest of payloads with an ECDSA key,
+// producing ASN.1 encoded signatures.
+type ECDSASigner struct {
+	keyID string
+	key   *ecdsa.PrivateKey
+}
+
+// NewECDSASigner returns an ECDSASigner using key, published to partners
+// under keyID.
+func NewECDSASigner(keyID string, key *ecdsa.PrivateKey) *ECDSASigner {
+	return &ECDSASigner{keyID: keyID, key: key}
+}
+
+// ParseECDSASigner returns an ECDSASigner using the PEM encoded EC or PKCS8
+// private key in data.
+func ParseECDSASigner(keyID string, data []byte) (*ECDSASigner, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key is a %T, not an ECDSA key", key)
+	}
+	return NewECDSASigner(keyID, ecKey), nil
+}
+
+// KeyID implements Signer.
+func (s *ECDSASigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign implements Signer.
+func (s *ECDSASigner) Sign(payload []byte) ([]byte, error) {
+	digest := sha256.Sum256(payload)
+	return ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+}
+
+// Verify reports whether signature is a valid signature of payload by the
+// private key matching key, as partners would check it.
+func Verify(key *ecdsa.PublicKey, payload, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+	return ecdsa.VerifyASN1(key, digest[:], signature)
+}
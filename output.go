@@ -0,0 +1,201 @@
+package hypercredscan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// FormatOptions carries what output formats may need beyond the writer.
+type FormatOptions struct {
+	// Config supplies provider descriptions to formats that include them.
+	Config *config.Config
+	// Secrets controls how formats that export findings for machine
+	// consumption represent the secret value. Defaults to SecretsOmitted.
+	Secrets SecretMode
+}
+
+// outputFormats maps the names accepted by NewFormatMatchProcessor to their
+// constructors.
+var outputFormats = map[string]func(w io.Writer, opts FormatOptions) findings.Processor{
+	"text": func(w io.Writer, _ FormatOptions) findings.Processor {
+		return NewTextMatchProcessor(w)
+	},
+	"json": func(w io.Writer, _ FormatOptions) findings.Processor {
+		return NewJSONMatchProcessor(w)
+	},
+	"jsonl": func(w io.Writer, opts FormatOptions) findings.Processor {
+		return NewJSONLinesMatchProcessor(w, opts.Secrets)
+	},
+	"csv": func(w io.Writer, opts FormatOptions) findings.Processor {
+		return NewCSVMatchProcessor(w, opts.Secrets)
+	},
+	"sarif": func(w io.Writer, opts FormatOptions) findings.Processor {
+		return NewSARIFMatchProcessor(w, opts.Config)
+	},
+}
+
+// OutputFormats returns the names of the supported output formats, sorted.
+func OutputFormats() []string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewFormatMatchProcessor returns a match processor that writes findings to w
+// in the named format.
+func NewFormatMatchProcessor(format string, w io.Writer, opts FormatOptions) (findings.Processor, error) {
+	newProcessor, ok := outputFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (supported: %s)", format, strings.Join(OutputFormats(), ", "))
+	}
+	return newProcessor(w, opts), nil
+}
+
+// TextMatchProcessor writes one human-readable line per finding as soon as it
+// is processed.
+type TextMatchProcessor struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTextMatchProcessor returns a TextMatchProcessor writing to w.
+func NewTextMatchProcessor(w io.Writer) findings.Processor {
+	return &TextMatchProcessor{w: w}
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *TextMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	severity, suffix := "", ""
+	if f.Severity != "" {
+		severity = " [" + string(f.Severity) + "]"
+	}
+	if f.LikelyTest {
+		suffix = " (likely test value)"
+	}
+	if f.Validation != nil {
+		suffix += " (" + string(f.Validation.Status) + ")"
+	}
+	_, err := fmt.Fprintf(p.w, "%s:%d:%d: %s%s %s%s\n", f.Path, f.Line, f.Column, f.Provider, severity, f.RedactedSecret(), suffix)
+	return err
+}
+
+// Flush implements findings.Processor.
+func (p *TextMatchProcessor) Flush() error {
+	return nil
+}
+
+// jsonFinding is the serialized form of a finding. The secret itself is never
+// written, only its redacted form and keyed fingerprint.
+type jsonFinding struct {
+	Provider    string  `json:"provider"`
+	BlobSHA     string  `json:"blob_sha"`
+	Path        string  `json:"path"`
+	Start       uint64  `json:"start"`
+	End         uint64  `json:"end"`
+	Line        int     `json:"line"`
+	Column      int     `json:"column"`
+	Redacted    string  `json:"redacted"`
+	Fingerprint string  `json:"fingerprint"`
+	LikelyTest  bool    `json:"likely_test,omitempty"`
+	Confidence  float64 `json:"confidence"`
+	Severity    string  `json:"severity"`
+	Vendor      string  `json:"vendor"`
+	Name        string  `json:"provider_name"`
+	Environment string  `json:"environment"`
+	DocsURL     string  `json:"docs_url,omitempty"`
+	Revocation  string  `json:"revocation,omitempty"`
+
+	Annotations  map[string]string      `json:"annotations,omitempty"`
+	Validation   *findings.Validation   `json:"validation,omitempty"`
+	Alternatives []findings.Alternative `json:"alternatives,omitempty"`
+
+	ConfigVersion string `json:"config_version,omitempty"`
+	PatternHash   string `json:"pattern_hash,omitempty"`
+}
+
+func newJSONFinding(f *findings.Finding) jsonFinding {
+	metadata := f.ProviderMetadata()
+	return jsonFinding{
+		Provider:    f.Provider,
+		BlobSHA:     f.BlobSHA,
+		Path:        f.Path,
+		Start:       f.Start,
+		End:         f.End,
+		Line:        f.Line,
+		Column:      f.Column,
+		Redacted:    f.RedactedSecret(),
+		Fingerprint: f.Fingerprint,
+		LikelyTest:  f.LikelyTest,
+		Confidence:  f.Confidence,
+		Severity:    string(f.Severity),
+		Vendor:      metadata.Vendor,
+		Name:        metadata.Name,
+		Environment: string(metadata.Environment),
+		DocsURL:     metadata.DocsURL,
+		Revocation:  metadata.Revocation,
+
+		Annotations:  f.Annotations,
+		Validation:   f.Validation,
+		Alternatives: f.Alternatives,
+
+		ConfigVersion: f.ConfigVersion,
+		PatternHash:   f.PatternHash,
+	}
+}
+
+// JSONMatchProcessor collects findings and writes them as a single JSON array
+// on Flush, sorted by path and offset so output is stable across runs.
+type JSONMatchProcessor struct {
+	mu       sync.Mutex
+	w        io.Writer
+	findings []jsonFinding
+}
+
+// NewJSONMatchProcessor returns a JSONMatchProcessor writing to w.
+func NewJSONMatchProcessor(w io.Writer) findings.Processor {
+	return &JSONMatchProcessor{w: w}
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *JSONMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.findings = append(p.findings, newJSONFinding(f))
+	return nil
+}
+
+// Flush implements findings.Processor.
+func (p *JSONMatchProcessor) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sort.Slice(p.findings, func(i, j int) bool {
+		a, b := p.findings[i], p.findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.Provider < b.Provider
+	})
+	out := p.findings
+	if out == nil {
+		out = []jsonFinding{}
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
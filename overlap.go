@@ -0,0 +1,238 @@
+package hypercredscan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/go-stats"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+// OverlapOptions configures AnalyzeOverlaps.
+type OverlapOptions struct {
+	// Expected declares the overlaps that are not flagged. Defaults to
+	// config.LoadDefaultExpectedOverlaps.
+	Expected *config.ExpectedOverlaps
+}
+
+// Overlap records that a provider fired on the span another provider matched
+// in its own examples.
+type Overlap struct {
+	// Provider is the provider whose examples were scanned and FiredBy the
+	// provider that matched the same span.
+	Provider string `json:"provider"`
+	FiredBy  string `json:"fired_by"`
+	// Examples is how many of Provider's examples FiredBy fired on, out of
+	// Total.
+	Examples int `json:"examples"`
+	Total    int `json:"total"`
+	// Expected is set when the overlap is declared in the expected overlaps.
+	Expected bool `json:"expected"`
+	// Context is set when FiredBy recognizes context rather than secrets,
+	// having severity info.
+	Context bool `json:"context"`
+}
+
+// Unexpected reports whether the overlap is neither declared nor caused by a
+// context provider, and so should be investigated.
+func (o Overlap) Unexpected() bool {
+	return !o.Expected && !o.Context
+}
+
+func (o Overlap) status() string {
+	switch {
+	case o.Expected:
+		return "expected"
+	case o.Context:
+		return "context"
+	}
+	return "UNEXPECTED"
+}
+
+// OverlapReport is the sparse matrix of which providers fire on which other
+// providers' examples.
+type OverlapReport struct {
+	// Examples is the number of examples scanned per provider, and Missed
+	// the number of those the provider did not match itself.
+	Examples map[string]int `json:"examples"`
+	Missed   map[string]int `json:"missed"`
+	// Overlaps are sorted by provider, then by the provider firing.
+	Overlaps []Overlap `json:"overlaps"`
+}
+
+// AnalyzeOverlaps scans each example on its own with scanner's database, which
+// should be built from the full provider configuration, and records every
+// other provider matching a span that overlaps the example provider's own
+// match. It looks at the raw matches Hyperscan reports, before the filter
+// chain, alternative match resolution and suppressors of Scanner.Scan, since
+// those exist to hide the very collisions the report is after. examples maps
+// provider names to example content containing their tokens; examples of
+// providers scanner does not know are skipped.
+func AnalyzeOverlaps(ctx context.Context, scanner *Scanner, examples map[string][]string, opts OverlapOptions) (*OverlapReport, error) {
+	if opts.Expected == nil {
+		opts.Expected = config.LoadDefaultExpectedOverlaps()
+	}
+	report := &OverlapReport{
+		Examples: make(map[string]int),
+		Missed:   make(map[string]int),
+		Overlaps: []Overlap{},
+	}
+	for provider, inputs := range examples {
+		if _, ok := scanner.metadata[provider]; !ok {
+			continue
+		}
+		fired := make(map[string]int)
+		for _, input := range inputs {
+			found, err := scanner.rawMatches(ctx, []byte(input))
+			if err != nil {
+				return nil, fmt.Errorf("scanning example of %s: %w", provider, err)
+			}
+			report.Examples[provider]++
+			others := overlapping(provider, found)
+			if others == nil {
+				report.Missed[provider]++
+				continue
+			}
+			for other := range others {
+				fired[other]++
+			}
+		}
+		for other, count := range fired {
+			report.Overlaps = append(report.Overlaps, Overlap{
+				Provider: provider,
+				FiredBy:  other,
+				Examples: count,
+				Total:    len(inputs),
+				Expected: opts.Expected.Lookup(provider, other) != nil,
+				Context:  scanner.metadata[other].Severity == config.SeverityInfo,
+			})
+		}
+	}
+	sort.Slice(report.Overlaps, func(i, j int) bool {
+		a, b := report.Overlaps[i], report.Overlaps[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.FiredBy < b.FiredBy
+	})
+	return report, nil
+}
+
+// rawMatches returns every match the scanner's database reports in content,
+// unfiltered.
+func (s *Scanner) rawMatches(ctx context.Context, content []byte) ([]rawMatch, error) {
+	scratch, err := s.acquireScratch()
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseScratch(scratch)
+
+	var found []rawMatch
+	callback := func(providerIdx uint, _ string, _ []byte, _ []byte, from, to uint64, _ *BlobContext, _ stats.Client) error {
+		found = append(found, rawMatch{Provider: s.providers[providerIdx].Name, Start: from, End: to})
+		return nil
+	}
+	dbs := []*DatabaseWithCallback{{Database: s.db, Callback: callback}}
+	if err := ScanWithScratchV2(ctx, s.logger, s.reporter, s.statter, &contentBlob{sha: BlobSHA(content), content: content}, dbs, scratch, false); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// rawMatch is a span a provider's pattern matched.
+type rawMatch struct {
+	Provider   string
+	Start, End uint64
+}
+
+// overlapping returns the providers other than provider whose matches overlap
+// one of provider's, or nil if provider matched nothing.
+func overlapping(provider string, found []rawMatch) map[string]bool {
+	var own []rawMatch
+	for _, f := range found {
+		if f.Provider == provider {
+			own = append(own, f)
+		}
+	}
+	if len(own) == 0 {
+		return nil
+	}
+	others := make(map[string]bool)
+	for _, f := range found {
+		if f.Provider == provider {
+			continue
+		}
+		for _, o := range own {
+			if f.Start < o.End && o.Start < f.End {
+				others[f.Provider] = true
+				break
+			}
+		}
+	}
+	return others
+}
+
+// Unexpected returns the overlaps that should be investigated.
+func (r *OverlapReport) Unexpected() []Overlap {
+	var unexpected []Overlap
+	for _, o := range r.Overlaps {
+		if o.Unexpected() {
+			unexpected = append(unexpected, o)
+		}
+	}
+	return unexpected
+}
+
+// Unobserved returns the overlaps declared in expected that the report does
+// not show in either direction, so declarations that no longer describe the
+// patterns can be removed.
+func (r *OverlapReport) Unobserved(expected *config.ExpectedOverlaps) []*config.ExpectedOverlap {
+	observed := make(map[[2]string]bool)
+	for _, o := range r.Overlaps {
+		observed[[2]string{o.Provider, o.FiredBy}] = true
+		observed[[2]string{o.FiredBy, o.Provider}] = true
+	}
+	var unobserved []*config.ExpectedOverlap
+	for _, declared := range expected.All() {
+		if !observed[[2]string{declared.Providers[0], declared.Providers[1]}] {
+			unobserved = append(unobserved, declared)
+		}
+	}
+	return unobserved
+}
+
+// WriteTo writes the report as stable, line oriented text suitable for a
+// golden file: one line per overlap followed by one per provider missing
+// some of its own examples.
+func (r *OverlapReport) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, o := range r.Overlaps {
+		fmt.Fprintf(bw, "%s: %s fires on %d/%d examples (%s)\n", o.Provider, o.FiredBy, o.Examples, o.Total, o.status())
+	}
+	missed := make([]string, 0, len(r.Missed))
+	for provider := range r.Missed {
+		missed = append(missed, provider)
+	}
+	sort.Strings(missed)
+	for _, provider := range missed {
+		fmt.Fprintf(bw, "%s: misses %d/%d of its own examples\n", provider, r.Missed[provider], r.Examples[provider])
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
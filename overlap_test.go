@@ -0,0 +1,57 @@
+package hypercredscan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestAnalyzeOverlapsSeesSuppressedMatches(t *testing.T) {
+	t.Parallel()
+	const providerFile = `providers:
+  - name: OVERLAP_TOKEN
+    pattern: 'ovt_[a-z0-9]{16}'
+    metadata:
+      vendor: Example
+      name: Example Token
+      severity: high
+      environment: live
+  - name: OVERLAP_DIGEST
+    pattern: 'digest-ovt_[a-z0-9]{16}'
+    metadata:
+      vendor: Example
+      name: Example Digest
+      severity: medium
+      environment: live
+    suppression:
+      region: match
+`
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	require.NoError(t, os.WriteFile(path, []byte(providerFile), 0o600))
+	cfg, declarations, err := config.LoadConfigPath(path)
+	require.NoError(t, err)
+	scanner, err := NewScanner(cfg, WithDeclarations(declarations))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	// Scan hides the collision: the suppressor cancels the token.
+	example := "digest-ovt_0123456789abcdef"
+	found, err := scanner.Scan(context.Background(), &findings.Blob{Content: []byte(example)})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	report, err := AnalyzeOverlaps(context.Background(), scanner, map[string][]string{
+		"OVERLAP_TOKEN": {example, "token: ovt_fedcba9876543210"},
+		"UNKNOWN_TOKEN": {example},
+	}, OverlapOptions{Expected: &config.ExpectedOverlaps{}})
+	require.NoError(t, err)
+	require.Equal(t, []Overlap{{Provider: "OVERLAP_TOKEN", FiredBy: "OVERLAP_DIGEST", Examples: 1, Total: 2}}, report.Overlaps)
+	require.Equal(t, map[string]int{"OVERLAP_TOKEN": 2}, report.Examples)
+	require.Empty(t, report.Missed)
+}
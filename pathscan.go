@@ -0,0 +1,290 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// DefaultMaxFileSize is the largest file ScanPaths reads unless told otherwise.
+const DefaultMaxFileSize = 10 << 20 // 10mb
+
+// binarySniffLength is how much of a file is inspected for NUL bytes, the same
+// heuristic git uses to decide whether content is binary.
+const binarySniffLength = 8000
+
+var (
+	binaryExtensions = extensionSet(
+		".png", ".jpg", ".jpeg", ".gif", ".bmp", ".ico", ".webp", ".tiff", ".psd",
+		".mp3", ".mp4", ".mov", ".avi", ".wav", ".flac", ".ogg", ".webm",
+		".ttf", ".otf", ".woff", ".woff2", ".eot",
+		".exe", ".dll", ".so", ".dylib", ".o", ".a", ".lib", ".bin", ".class", ".pyc",
+		".pdf", ".doc", ".xls", ".ppt",
+	)
+	archiveExtensions = extensionSet(
+		".zip", ".jar", ".war", ".ear", ".apk", ".nupkg", ".whl",
+		".docx", ".xlsx", ".pptx", ".tar", ".tgz", ".gz",
+	)
+)
+
+func extensionSet(values ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
+
+// PathScanOptions controls which files ScanPaths reads and how.
+type PathScanOptions struct {
+	// Workers is the number of files scanned concurrently. Defaults to
+	// runtime.NumCPU().
+	Workers int
+	// MaxFileSize skips files, and archive entries, larger than this many
+	// bytes. Defaults to DefaultMaxFileSize; negative disables the limit.
+	MaxFileSize int64
+	// IgnoreGitignore disables .gitignore handling.
+	IgnoreGitignore bool
+	// IncludeBinary scans files that look binary instead of skipping them.
+	IncludeBinary bool
+	// ScanArchives expands zip and tar archives and scans their entries.
+	ScanArchives bool
+	// Filter, if set, decides which findings of each file are reported.
+	Filter findings.Filter
+	// Reporter is told about archives that cannot be expanded, such as
+	// truncated ones, which are then scanned as plain files. Nil means such
+	// errors are dropped.
+	Reporter ExceptionReporter
+}
+
+func (o PathScanOptions) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.NumCPU()
+}
+
+func (o PathScanOptions) maxFileSize() int64 {
+	switch {
+	case o.MaxFileSize < 0:
+		return 0
+	case o.MaxFileSize == 0:
+		return DefaultMaxFileSize
+	default:
+		return o.MaxFileSize
+	}
+}
+
+// ScanPaths walks each of paths, which may be files or directories, scans
+// every eligible file with scanner and hands the resulting findings to
+// processor. Files are scanned in parallel; the first error stops the walk.
+func ScanPaths(ctx context.Context, scanner *Scanner, paths []string, opts PathScanOptions, processor findings.Processor) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make(chan string)
+	errs := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+		cancel()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				if err := scanFile(ctx, scanner, file, opts, processor); err != nil {
+					fail(fmt.Errorf("%s: %w", file, err))
+					return
+				}
+			}
+		}()
+	}
+
+	walkErr := func() error {
+		defer close(files)
+		for _, root := range paths {
+			if err := walkPath(ctx, root, opts, files); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	wg.Wait()
+
+	if walkErr != nil && !errors.Is(walkErr, context.Canceled) {
+		return walkErr
+	}
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
+	return ctx.Err()
+}
+
+// walkPath sends every file under root that is not excluded by .gitignore to
+// files.
+func walkPath(ctx context.Context, root string, opts PathScanOptions, files chan<- string) error {
+	// Gitignores are keyed by directory as WalkDir reports it, which is clean
+	// below root; root must match, or "repo/" would lose its own .gitignore.
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		select {
+		case files <- root:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ignores := map[string]*gitignore{}
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		parent := ignores[filepath.Dir(file)]
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if rel != "." && !opts.IgnoreGitignore && parent.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			gi := parent
+			if !opts.IgnoreGitignore {
+				base := rel
+				if base == "." {
+					base = ""
+				}
+				if gi, err = loadGitignore(parent, filepath.Join(file, ".gitignore"), base); err != nil {
+					return err
+				}
+			}
+			ignores[file] = gi
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if !opts.IgnoreGitignore && parent.ignored(rel, false) {
+			return nil
+		}
+		select {
+		case files <- file:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+func scanFile(ctx context.Context, scanner *Scanner, file string, opts PathScanOptions, processor findings.Processor) error {
+	ext := strings.ToLower(filepath.Ext(file))
+	_, archiveExt := archiveExtensions[ext]
+	_, binaryExt := binaryExtensions[ext]
+	if !opts.IncludeBinary && binaryExt {
+		return nil
+	}
+
+	maxSize := opts.maxFileSize()
+	if maxSize > 0 {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxSize {
+			return nil
+		}
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	blob := &findings.Blob{Path: filepath.ToSlash(file), Content: content}
+	if isArchive(content) {
+		if !opts.ScanArchives {
+			if archiveExt || !opts.IncludeBinary {
+				return nil
+			}
+			return ScanBlob(ctx, scanner, blob, opts.Filter, processor)
+		}
+		entries, err := expandArchive(blob, maxSize, 0)
+		if err != nil {
+			// One bad archive, or a file merely starting like one, must not
+			// stop the walk.
+			if opts.Reporter != nil {
+				opts.Reporter.Report(ctx, fmt.Errorf("expanding archive %s: %w", file, err), map[string]interface{}{"path": file})
+			}
+			if !opts.IncludeBinary && looksBinary(content) {
+				return nil
+			}
+			return ScanBlob(ctx, scanner, blob, opts.Filter, processor)
+		}
+		for _, entry := range entries {
+			if !opts.IncludeBinary && looksBinary(entry.Content) {
+				continue
+			}
+			if err := ScanBlob(ctx, scanner, entry, opts.Filter, processor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !opts.IncludeBinary && looksBinary(content) {
+		return nil
+	}
+	return ScanBlob(ctx, scanner, blob, opts.Filter, processor)
+}
+
+// ScanBlob scans blob, applies filter, if any, and hands the surviving
+// findings to processor.
+func ScanBlob(ctx context.Context, scanner *Scanner, blob *findings.Blob, filter findings.Filter, processor findings.Processor) error {
+	found, err := scanner.Scan(ctx, blob)
+	if err != nil {
+		return err
+	}
+	if filter != nil {
+		found = filter.Filter(ctx, blob, found)
+	}
+	for _, f := range found {
+		if err := processor.ProcessFinding(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func looksBinary(content []byte) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
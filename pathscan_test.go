@@ -0,0 +1,181 @@
+package hypercredscan
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+const pathScanToken = "AKIAJ7PVADC4BIKJFFP9"
+
+// tarball returns a tar archive of files, gzipped if compress is set.
+func tarball(t *testing.T, files map[string][]byte, compress bool) []byte {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if !compress {
+		return buf.Bytes()
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return gz.Bytes()
+}
+
+// scanPaths runs ScanPaths over paths and returns the paths of the findings,
+// sorted.
+func scanPaths(t *testing.T, scanner *Scanner, paths []string, opts PathScanOptions) []string {
+	t.Helper()
+	processor := &collectingProcessor{}
+	require.NoError(t, ScanPaths(context.Background(), scanner, paths, opts, processor))
+	found := []string{}
+	for _, f := range processor.findings {
+		require.Equal(t, pathScanToken, string(f.Secret))
+		found = append(found, f.Path)
+	}
+	sort.Strings(found)
+	return found
+}
+
+func TestScanPaths(t *testing.T) {
+	t.Parallel()
+	scanner, err := NewScanner(getConfig("AWS_KEYID"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	secret := []byte("aws_access_key_id = " + pathScanToken + "\n")
+	inner := tarball(t, map[string][]byte{"nested.env": secret}, false)
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		".gitignore":         []byte("ignored/\n*.log\n"),
+		"app.env":            secret,
+		"ignored/app.env":    secret,
+		"debug.log":          secret,
+		"sub/.gitignore":     []byte("local.env\n"),
+		"sub/local.env":      secret,
+		"sub/deploy.sh":      secret,
+		"large.env":          append(bytes.Repeat([]byte("#"), 10000), secret...),
+		"binary.dat":         append([]byte{0}, secret...),
+		"image.png":          secret,
+		".git/config":        secret,
+		"dist/bundle.tar.gz": tarball(t, map[string][]byte{"config.env": secret, "inner.tar": inner}, true),
+		"dist/large.tar":     tarball(t, map[string][]byte{"large.env": append(bytes.Repeat([]byte("#"), 10000), secret...)}, false),
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+	}
+	rel := func(paths ...string) []string {
+		for i, p := range paths {
+			paths[i] = filepath.ToSlash(filepath.Join(dir, p))
+		}
+		return paths
+	}
+
+	opts := PathScanOptions{Workers: 2, MaxFileSize: 8192}
+	require.Equal(t, rel("app.env", "sub/deploy.sh"), scanPaths(t, scanner, []string{dir}, opts))
+	// The root .gitignore applies however the root is spelled.
+	require.Equal(t, rel("app.env", "sub/deploy.sh"), scanPaths(t, scanner, []string{dir + string(filepath.Separator)}, opts))
+	require.Equal(t, rel("app.env", "sub/deploy.sh"), scanPaths(t, scanner, []string{filepath.Join(dir, "sub", "..")}, opts))
+
+	opts.ScanArchives = true
+	require.Equal(t, rel(
+		"app.env",
+		"dist/bundle.tar.gz!config.env",
+		"dist/bundle.tar.gz!inner.tar!nested.env",
+		"sub/deploy.sh",
+	), scanPaths(t, scanner, []string{dir}, opts), "archives over the size limit are skipped")
+
+	opts = PathScanOptions{MaxFileSize: -1, IgnoreGitignore: true, IncludeBinary: true}
+	found := scanPaths(t, scanner, []string{dir}, opts)
+	require.Equal(t, rel(
+		"app.env",
+		"binary.dat",
+		"debug.log",
+		"ignored/app.env",
+		"image.png",
+		"large.env",
+		"sub/deploy.sh",
+		"sub/local.env",
+	), found, "version control metadata is never scanned")
+
+	// A file named explicitly is scanned even if a .gitignore excludes it.
+	require.Equal(t, rel("debug.log"), scanPaths(t, scanner, rel("debug.log"), PathScanOptions{}))
+
+	processor := &collectingProcessor{}
+	err = ScanPaths(context.Background(), scanner, rel("missing.env"), PathScanOptions{}, processor)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestScanPathsReportsBrokenArchives(t *testing.T) {
+	t.Parallel()
+	scanner, err := NewScanner(getConfig("AWS_KEYID"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	secret := []byte("aws_access_key_id = " + pathScanToken + "\n")
+	bundle := tarball(t, map[string][]byte{"config.env": secret}, true)
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"app.env":          secret,
+		"truncated.tar.gz": bundle[:len(bundle)/2],
+		// Text that happens to start with the gzip magic number.
+		"notes.txt": append([]byte{0x1f, 0x8b, '\n'}, secret...),
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+
+	reporter := &recordingReporter{}
+	found := scanPaths(t, scanner, []string{dir}, PathScanOptions{ScanArchives: true, Reporter: reporter})
+	require.Equal(t, []string{
+		filepath.ToSlash(filepath.Join(dir, "app.env")),
+		filepath.ToSlash(filepath.Join(dir, "notes.txt")),
+	}, found)
+	reported := reporter.reported()
+	require.Len(t, reported, 2)
+	for _, err := range reported {
+		require.ErrorContains(t, err, "expanding archive")
+	}
+}
+
+func TestScanPathsStopsOnCancel(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.LoadCustomConfig(getConfig("AWS_KEYID").HyperscanProviders())
+	require.NoError(t, err)
+	scanner, err := NewScanner(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	dir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, strings.Repeat("a", i+1)+".env"), []byte(pathScanToken), 0o600))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ScanPaths(ctx, scanner, []string{dir}, PathScanOptions{}, &collectingProcessor{})
+	require.ErrorIs(t, err, context.Canceled)
+}
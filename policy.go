@@ -0,0 +1,239 @@
+package hypercredscan
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+// DefaultPolicyCacheSize is the number of provider subsets a PolicySource
+// keeps compiled by default.
+const DefaultPolicyCacheSize = 16
+
+// ErrPolicySourceClosed is returned when acquiring a scanner from a closed
+// PolicySource.
+var ErrPolicySourceClosed = errors.New("policy source is closed")
+
+// ErrNoProvidersEnabled is returned when the policy enables no providers for
+// an identity.
+var ErrNoProvidersEnabled = errors.New("policy enables no providers")
+
+// Identity is who a scan is for. The zero Identity is an anonymous caller,
+// scanned with the providers that are not opt-in.
+type Identity struct {
+	Tenant     string
+	Repository string
+}
+
+// PolicySourceOptions configures a PolicySource. Zero fields take the
+// defaults noted.
+type PolicySourceOptions struct {
+	// CacheSize defaults to DefaultPolicyCacheSize.
+	CacheSize int
+	// ScannerOptions are applied to every scanner the source builds.
+	ScannerOptions []ScannerOption
+	// Reporter is told about scanners that fail to close once evicted. Nil
+	// means such errors are dropped.
+	Reporter ExceptionReporter
+}
+
+// subset is a scanner over the providers a policy enables for some
+// identities. Its scanner is closed once it has been evicted and the last
+// lease on it released.
+type subset struct {
+	key      string
+	ready    chan struct{}
+	scanner  *Scanner
+	err      error
+	inflight sync.WaitGroup
+}
+
+// PolicySource is a ScannerSource that scans each identity with only the
+// providers a config.Policy enables for it. It leases scanners from another
+// source, such as a ConfigManager, and builds a scanner for each distinct
+// subset of their providers, keeping the most recently used ones compiled.
+// Identities the policy leaves with every provider are handed the underlying
+// scanner itself.
+type PolicySource struct {
+	source ScannerSource
+	policy *config.Policy
+	opts   PolicySourceOptions
+
+	mu sync.Mutex
+	// lru orders the subsets from most to least recently used; subsets
+	// indexes its elements by key.
+	lru     *list.List
+	subsets map[string]*list.Element
+	closed  bool
+}
+
+// NewPolicySource returns a PolicySource applying policy to the scanners of
+// source.
+func NewPolicySource(source ScannerSource, policy *config.Policy, opts PolicySourceOptions) *PolicySource {
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = DefaultPolicyCacheSize
+	}
+	return &PolicySource{
+		source:  source,
+		policy:  policy,
+		opts:    opts,
+		lru:     list.New(),
+		subsets: make(map[string]*list.Element),
+	}
+}
+
+// Acquire leases a scanner over the providers enabled for identity. It
+// implements ScannerSource. The first scan of a subset not in the cache
+// compiles its database.
+func (p *PolicySource) Acquire(identity Identity) (*Lease, error) {
+	base, err := p.source.Acquire(identity)
+	if err != nil {
+		return nil, err
+	}
+	all := base.Scanner.Config().HyperscanProviders()
+	providers := p.policy.Enabled(all, identity.Tenant, identity.Repository)
+	if len(providers) == len(all) {
+		return base, nil
+	}
+	if len(providers) == 0 {
+		base.Release()
+		return nil, fmt.Errorf("%w for tenant %q, repository %q", ErrNoProvidersEnabled, identity.Tenant, identity.Repository)
+	}
+
+	s, err := p.subset(base.Scanner, providers)
+	if err != nil {
+		base.Release()
+		return nil, err
+	}
+	return &Lease{
+		Scanner: s.scanner,
+		Filter:  base.Filter,
+		release: sync.OnceFunc(func() {
+			s.inflight.Done()
+			base.Release()
+		}),
+	}, nil
+}
+
+// subset returns the cached subset of providers from base's configuration,
+// compiling it if needed, with a lease taken on it.
+func (p *PolicySource) subset(base *Scanner, providers []*config.ProviderConfig) (*subset, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", base.ConfigVersion())
+	for _, provider := range providers {
+		fmt.Fprintf(h, "%s\n", provider.Name)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPolicySourceClosed
+	}
+	if elem, ok := p.subsets[key]; ok {
+		p.lru.MoveToFront(elem)
+		s := elem.Value.(*subset)
+		s.inflight.Add(1)
+		p.mu.Unlock()
+		<-s.ready
+		if s.err != nil {
+			s.inflight.Done()
+			return nil, s.err
+		}
+		return s, nil
+	}
+	s := &subset{key: key, ready: make(chan struct{})}
+	s.inflight.Add(1)
+	p.subsets[key] = p.lru.PushFront(s)
+	var evicted []*subset
+	for p.lru.Len() > p.opts.CacheSize {
+		evicted = append(evicted, p.remove(p.lru.Back()))
+	}
+	p.mu.Unlock()
+	for _, e := range evicted {
+		go p.retire(e)
+	}
+
+	// Compile outside the lock, so cached subsets stay available meanwhile;
+	// concurrent acquirers of this subset wait on ready.
+	s.scanner, s.err = p.build(base, providers)
+	close(s.ready)
+	if s.err != nil {
+		p.mu.Lock()
+		if elem, ok := p.subsets[key]; ok && elem.Value == s {
+			p.remove(elem)
+		}
+		p.mu.Unlock()
+		s.inflight.Done()
+		return nil, s.err
+	}
+	return s, nil
+}
+
+// build compiles a scanner over providers, with the declarations of base.
+func (p *PolicySource) build(base *Scanner, providers []*config.ProviderConfig) (*Scanner, error) {
+	cfg, err := config.LoadCustomConfig(providers)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]ScannerOption{WithDeclarations(base.Declarations())}, p.opts.ScannerOptions...)
+	return NewScanner(cfg, opts...)
+}
+
+// remove drops elem from the cache. p.mu must be held.
+func (p *PolicySource) remove(elem *list.Element) *subset {
+	s := p.lru.Remove(elem).(*subset)
+	delete(p.subsets, s.key)
+	return s
+}
+
+// retire closes s's scanner once it is compiled and its last lease released.
+func (p *PolicySource) retire(s *subset) {
+	<-s.ready
+	s.inflight.Wait()
+	if s.err != nil {
+		return
+	}
+	if err := s.scanner.Close(); err != nil && p.opts.Reporter != nil {
+		p.opts.Reporter.Report(context.Background(), fmt.Errorf("closing evicted policy scanner: %w", err), nil)
+	}
+}
+
+// Cached returns the number of provider subsets currently cached.
+func (p *PolicySource) Cached() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// Close stops handing out scanners and closes the cached ones once their
+// leases are released, waiting for them. The underlying source is not
+// closed.
+func (p *PolicySource) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var subsets []*subset
+	for p.lru.Len() > 0 {
+		subsets = append(subsets, p.remove(p.lru.Front()))
+	}
+	p.mu.Unlock()
+	var errs []error
+	for _, s := range subsets {
+		<-s.ready
+		s.inflight.Wait()
+		if s.err == nil {
+			errs = append(errs, s.scanner.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package hypercredscan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const testPolicy = `opt_in: [ADAFRUIT_AIO_KEY]
+tenants:
+  acme:
+    enable: [ADAFRUIT_AIO_KEY]
+    repositories:
+      acme/legacy:
+        disable: [AWS_KEYID]
+  quiet:
+    disable: [AWS_KEYID]
+`
+
+// policyProviders scans content with a lease for identity and returns the
+// providers found.
+func policyProviders(t *testing.T, source ScannerSource, identity Identity, content string) []string {
+	t.Helper()
+	lease, err := source.Acquire(identity)
+	require.NoError(t, err)
+	defer lease.Release()
+	found, err := lease.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(content)})
+	require.NoError(t, err)
+	providers := []string{}
+	for _, f := range found {
+		providers = append(providers, f.Provider)
+	}
+	return providers
+}
+
+func TestPolicySource(t *testing.T) {
+	t.Parallel()
+	var providers []*config.ProviderConfig
+	for _, name := range []string{"ADAFRUIT_AIO_KEY", "AWS_KEYID"} {
+		providers = append(providers, getConfig(name).HyperscanProviders()...)
+	}
+	cfg, err := config.LoadCustomConfig(providers)
+	require.NoError(t, err)
+	scanner, err := NewScanner(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	policy, err := config.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	require.NoError(t, policy.Validate(providers))
+	source := NewPolicySource(StaticSource(scanner, nil), policy, PolicySourceOptions{CacheSize: 1})
+	t.Cleanup(func() { require.NoError(t, source.Close()) })
+
+	content := "aio_FMBo07xPM4e0Aj3eYjO23blItBvS AKIAJ7PVADC4BIKJFFP9"
+	require.Equal(t, []string{"AWS_KEYID"}, policyProviders(t, source, Identity{}, content), "opt-in providers are off by default")
+	require.Equal(t, []string{"ADAFRUIT_AIO_KEY", "AWS_KEYID"}, policyProviders(t, source, Identity{Tenant: "acme", Repository: "acme/api"}, content))
+	require.Equal(t, []string{"ADAFRUIT_AIO_KEY"}, policyProviders(t, source, Identity{Tenant: "acme", Repository: "acme/legacy"}, content))
+	require.Equal(t, 1, source.Cached(), "the cache is bounded")
+
+	lease, err := source.Acquire(Identity{Tenant: "acme"})
+	require.NoError(t, err)
+	require.Same(t, scanner, lease.Scanner, "identities with every provider enabled use the underlying scanner")
+	lease.Release()
+
+	_, err = source.Acquire(Identity{Tenant: "quiet"})
+	require.ErrorIs(t, err, ErrNoProvidersEnabled)
+
+	// A lease outlives the eviction of its subset.
+	held, err := source.Acquire(Identity{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ADAFRUIT_AIO_KEY"}, policyProviders(t, source, Identity{Tenant: "acme", Repository: "acme/legacy"}, content))
+	found, err := held.Scanner.Scan(context.Background(), &findings.Blob{Content: []byte(content)})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	held.Release()
+
+	require.NoError(t, source.Close())
+	_, err = source.Acquire(Identity{})
+	require.ErrorIs(t, err, ErrPolicySourceClosed)
+}
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+	_, err := config.ParsePolicy([]byte("tenants:\n  acme:\n    enable: [A]\n    disable: [A]\n"))
+	require.ErrorContains(t, err, "both enabled and disabled")
+	_, err = config.ParsePolicy([]byte("tenants:\n  acme:\n    repositories:\n      acme/api:\n"))
+	require.ErrorContains(t, err, "has no policy")
+
+	policy, err := config.ParsePolicy([]byte("opt_in: [GITHUB_PERSONAL_ACCESS_TOKN]\n"))
+	require.NoError(t, err)
+	require.ErrorContains(t, policy.Validate(config.GetDefaultConfig()), "GITHUB_PERSONAL_ACCESS_TOKN")
+
+	var none *config.Policy
+	require.Len(t, none.Enabled(config.GetDefaultConfig(), "acme", ""), len(config.GetDefaultConfig()))
+}
@@ -0,0 +1,252 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/github/go-stats"
+	"gopkg.in/yaml.v3"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/internal/pathglob"
+)
+
+// RepositoryConfigFile is the conventional name of a repository's scanner
+// configuration, read from the repository root.
+const RepositoryConfigFile = ".hypercredscan.yml"
+
+// allowlistSuppressedStat counts suppressions, tagged by rule and provider.
+const allowlistSuppressedStat = "hypercredscan.allowlist.suppressed"
+
+// RepositoryConfig is the repository-level configuration file.
+type RepositoryConfig struct {
+	Allowlist []AllowlistRule `yaml:"allowlist"`
+}
+
+// AllowlistRule suppresses findings matching all of its non-empty criteria.
+// Within a criterion, any entry matching is enough. For example a rule with
+// Paths and Providers suppresses findings of those providers in those paths.
+type AllowlistRule struct {
+	// ID names the rule in decisions and stats.
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+	// Providers limits the rule to these providers.
+	Providers []string `yaml:"providers"`
+	// Paths are globs matched against the finding's path relative to the
+	// repository root; "**" matches any number of directories.
+	Paths []string `yaml:"paths"`
+	// Fingerprints are exact finding fingerprints, as reported by the scanner.
+	Fingerprints []string `yaml:"fingerprints"`
+	// Tokens are regular expressions matched against the secret.
+	Tokens []string `yaml:"tokens"`
+}
+
+// LoadRepositoryConfig reads the configuration file at path.
+func LoadRepositoryConfig(path string) (*RepositoryConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg RepositoryConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+type allowlistRule struct {
+	AllowlistRule
+	providers    map[string]bool
+	fingerprints map[string]bool
+	tokens       []*regexp.Regexp
+}
+
+func (r *allowlistRule) matches(f *findings.Finding, path string) bool {
+	if len(r.providers) > 0 && !r.providers[f.Provider] {
+		return false
+	}
+	if len(r.fingerprints) > 0 && !r.fingerprints[f.Fingerprint] {
+		return false
+	}
+	if len(r.Paths) > 0 {
+		matched := false
+		for _, glob := range r.Paths {
+			if path != "" && pathglob.Match(glob, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.tokens) > 0 {
+		matched := false
+		for _, re := range r.tokens {
+			if re.Match(f.Secret) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowlist is a findings.Filter suppressing findings matched by any of its
+// rules. Each suppression is counted per rule, both in stats and in Counts, so
+// over-broad rules can be audited.
+type Allowlist struct {
+	rules   []*allowlistRule
+	root    string
+	statter stats.Client
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewAllowlist validates rules and returns an Allowlist applying them. Path
+// globs are matched against finding paths relative to root, the repository
+// root the configuration was read from, so a rule means the same whichever
+// directory the scanner runs in. An empty root matches paths as reported.
+func NewAllowlist(rules []AllowlistRule, root string, statter stats.Client) (*Allowlist, error) {
+	a := &Allowlist{root: root, statter: statter, counts: make(map[string]int64)}
+	ids := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("allowlist rule %d has no id", i)
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("allowlist rule %q is defined more than once", rule.ID)
+		}
+		ids[rule.ID] = true
+		if len(rule.Paths) == 0 && len(rule.Fingerprints) == 0 && len(rule.Tokens) == 0 {
+			return nil, fmt.Errorf("allowlist rule %q needs at least one of paths, fingerprints or tokens", rule.ID)
+		}
+
+		compiled := &allowlistRule{
+			AllowlistRule: rule,
+			providers:     allowlistSet(rule.Providers),
+			fingerprints:  allowlistSet(rule.Fingerprints),
+		}
+		for _, glob := range rule.Paths {
+			if err := pathglob.Validate(glob); err != nil {
+				return nil, fmt.Errorf("allowlist rule %q: %w", rule.ID, err)
+			}
+		}
+		for _, expr := range rule.Tokens {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("allowlist rule %q: %w", rule.ID, err)
+			}
+			compiled.tokens = append(compiled.tokens, re)
+		}
+		a.rules = append(a.rules, compiled)
+	}
+	return a, nil
+}
+
+// Name implements findings.Filter.
+func (a *Allowlist) Name() string {
+	return "allowlist"
+}
+
+// Filter implements findings.Filter.
+func (a *Allowlist) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	kept := found[:0]
+	for _, f := range found {
+		rule := a.match(f)
+		if rule == nil {
+			kept = append(kept, f)
+			continue
+		}
+		f.Decide(a.Name(), findings.OutcomeSuppress, "rule "+rule.ID)
+		a.count(rule.ID, f)
+	}
+	return kept
+}
+
+func (a *Allowlist) match(f *findings.Finding) *allowlistRule {
+	path := a.relative(f.Path)
+	for _, rule := range a.rules {
+		if rule.matches(f, path) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// relative returns path relative to the allowlist's root. Paths outside the
+// root, such as "-" for stdin, are returned as "", which no glob matches.
+func (a *Allowlist) relative(path string) string {
+	rel, ok := relativePath(a.root, path)
+	if !ok {
+		return ""
+	}
+	return rel
+}
+
+// relativePath returns the slash separated path relative to root, and whether
+// it is inside root. An empty root leaves path as it is.
+func relativePath(root, path string) (string, bool) {
+	if root == "" {
+		return path, true
+	}
+	rel, err := filepath.Rel(root, filepath.FromSlash(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (a *Allowlist) count(rule string, f *findings.Finding) {
+	a.mu.Lock()
+	a.counts[rule]++
+	a.mu.Unlock()
+	if a.statter != nil {
+		a.statter.Counter(allowlistSuppressedStat, stats.Tags{"rule": rule, "provider": f.Provider, "config_version": f.ConfigVersion}, 1)
+	}
+}
+
+// Counts returns how many findings each rule has suppressed, including rules
+// that have suppressed nothing.
+func (a *Allowlist) Counts() map[string]int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	counts := make(map[string]int64, len(a.rules))
+	for _, rule := range a.rules {
+		counts[rule.ID] = a.counts[rule.ID]
+	}
+	return counts
+}
+
+// UnusedRules returns the IDs of rules that have suppressed nothing, sorted.
+func (a *Allowlist) UnusedRules() []string {
+	var unused []string
+	for id, count := range a.Counts() {
+		if count == 0 {
+			unused = append(unused, id)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+func allowlistSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,260 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"hash/crc32"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// PathKind classifies the file a finding was found in.
+type PathKind string
+
+const (
+	PathSource   PathKind = "source"
+	PathTest     PathKind = "test"
+	PathDocs     PathKind = "docs"
+	PathLockfile PathKind = "lockfile"
+)
+
+var (
+	lockfileNames = allowlistSet([]string{
+		"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml",
+		"composer.lock", "Gopkg.lock", "go.sum", "Cargo.lock", "Gemfile.lock",
+		"poetry.lock", "Pipfile.lock", "packages.lock.json", "mix.lock",
+	})
+	testDirs   = allowlistSet([]string{"test", "tests", "spec", "specs", "__tests__", "testdata", "fixtures", "__fixtures__", "mocks"})
+	docsDirs   = allowlistSet([]string{"doc", "docs", "documentation", "examples", "example"})
+	docsExts   = allowlistSet([]string{".md", ".markdown", ".rst", ".adoc", ".txt"})
+	testSuffix = regexp.MustCompile(`(?i)(_test\.go|[._-](test|spec)\.[a-z]+|^test_.*\.py)$`)
+)
+
+// ClassifyPath returns the kind of file at the slash separated path p.
+func ClassifyPath(p string) PathKind {
+	base := path.Base(p)
+	if lockfileNames[base] {
+		return PathLockfile
+	}
+	if testSuffix.MatchString(base) {
+		return PathTest
+	}
+	dirs := strings.Split(strings.ToLower(path.Dir(p)), "/")
+	for _, dir := range dirs {
+		if testDirs[dir] {
+			return PathTest
+		}
+	}
+	for _, dir := range dirs {
+		if docsDirs[dir] {
+			return PathDocs
+		}
+	}
+	if docsExts[strings.ToLower(path.Ext(base))] {
+		return PathDocs
+	}
+	return PathSource
+}
+
+// ConfidenceScorer is a findings.Filter that sets the confidence score of
+// every finding from the signals described by a config.ConfidenceModel. It
+// keeps every finding; consumers threshold on the score. It should run after
+// PlaceholderFilter so the placeholder signal is available.
+type ConfidenceScorer struct {
+	model *config.ConfidenceModel
+	root  string
+	// specific holds the providers whose patterns start with a literal prefix
+	// of at least model.MinPrefixLength characters.
+	specific map[string]bool
+	keywords [][]byte
+}
+
+// NewConfidenceScorer returns a ConfidenceScorer for findings of providers.
+// Paths are classified relative to root, the repository root being scanned,
+// so the directories above it, such as a checkout in /src/tests, do not make
+// every file look like a test. An empty root classifies paths as reported.
+func NewConfidenceScorer(model *config.ConfidenceModel, providers []*config.ProviderConfig, root string) *ConfidenceScorer {
+	s := &ConfidenceScorer{model: model, root: root, specific: make(map[string]bool)}
+	for _, provider := range providers {
+		// Patterns Go cannot compile are treated as loose.
+		re, err := regexp.Compile(provider.Pattern)
+		if err != nil {
+			continue
+		}
+		if prefix, _ := re.LiteralPrefix(); len(prefix) >= model.MinPrefixLength {
+			s.specific[provider.Name] = true
+		}
+	}
+	for _, keyword := range model.Keywords {
+		s.keywords = append(s.keywords, []byte(strings.ToLower(keyword)))
+	}
+	return s
+}
+
+// Name implements findings.Filter.
+func (s *ConfidenceScorer) Name() string {
+	return "confidence"
+}
+
+// Filter implements findings.Filter.
+func (s *ConfidenceScorer) Filter(_ context.Context, blob *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	kind := PathSource
+	var content []byte
+	if blob != nil {
+		// Paths outside the root are classified as reported.
+		path, _ := relativePath(s.root, blob.Path)
+		kind = ClassifyPath(path)
+		content = blob.Content
+	}
+	var names []*findings.Finding
+	for _, f := range found {
+		if isNamePresence(f.Provider) {
+			names = append(names, f)
+		}
+	}
+	for _, f := range found {
+		f.Confidence = s.score(f, kind, content, names)
+	}
+	return found
+}
+
+func (s *ConfidenceScorer) score(f *findings.Finding, kind PathKind, content []byte, names []*findings.Finding) float64 {
+	w := s.model.Weights
+	score := s.model.Base
+
+	if s.specific[f.Provider] {
+		score += w.Specificity
+	} else {
+		score -= w.Specificity
+	}
+	score += w.Checksum * float64(checksumSignal(f.Secret))
+	score += w.Entropy * entropySignal(f.Secret)
+	if kind != PathSource {
+		score -= w.Path
+	}
+	if f.LikelyTest {
+		score -= w.Placeholder
+	}
+
+	if containsAny(s.before(f, content), s.keywords) {
+		score += w.Keyword
+	}
+	if s.vendorNamed(f, names) {
+		score += w.VendorName
+	}
+	return math.Max(0, math.Min(1, score))
+}
+
+// before returns the lower-cased window before the secret.
+func (s *ConfidenceScorer) before(f *findings.Finding, content []byte) []byte {
+	if content == nil || f.End > uint64(len(content)) {
+		return nil
+	}
+	window := uint64(s.model.KeywordWindow)
+	start := uint64(0)
+	if f.Start > window {
+		start = f.Start - window
+	}
+	return bytes.ToLower(content[start:f.Start])
+}
+
+// vendorNamed reports whether one of names, the name presence findings of the
+// blob, names f's vendor within the keyword window around f's secret. A name
+// inside the secret itself does not count.
+func (s *ConfidenceScorer) vendorNamed(f *findings.Finding, names []*findings.Finding) bool {
+	if isNamePresence(f.Provider) {
+		return false
+	}
+	vendor := f.ProviderMetadata().Vendor
+	if vendor == "" {
+		return false
+	}
+	window := uint64(s.model.KeywordWindow)
+	for _, name := range names {
+		if name.ProviderMetadata().Vendor != vendor {
+			continue
+		}
+		before := name.End <= f.Start && f.Start-name.End <= window
+		after := name.Start >= f.End && name.Start-f.End <= window
+		if before || after {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamePresence reports whether provider matches a vendor's name rather than
+// a secret.
+func isNamePresence(provider string) bool {
+	return strings.HasSuffix(provider, "_NAME_PRESENCE")
+}
+
+func containsAny(s []byte, words [][]byte) bool {
+	for _, word := range words {
+		if bytes.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// entropySignal maps the Shannon entropy of s to [-1, 1], relative to the
+// highest entropy a string of its length could have.
+func entropySignal(s []byte) float64 {
+	if len(s) < 2 {
+		return -1
+	}
+	var counts [256]int
+	for _, b := range s {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	// Random strings rarely reach the maximum, so most of the range is
+	// reached by 90% of it.
+	ratio := math.Min(1, entropy/(0.9*math.Log2(math.Min(float64(len(s)), 64))))
+	return 2*ratio - 1
+}
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// checksumPrefixes are the prefixes of tokens ending in a six character
+// base62 CRC32 of the 30 characters following the prefix.
+var checksumPrefixes = []string{"ghp_", "gho_", "ghu_", "ghs_", "npm_"}
+
+// checksumSignal returns 1 if secret carries a valid embedded checksum, -1 if
+// it carries an invalid one and 0 if its format has none.
+func checksumSignal(secret []byte) int {
+	for _, prefix := range checksumPrefixes {
+		if !bytes.HasPrefix(secret, []byte(prefix)) || len(secret) != len(prefix)+36 {
+			continue
+		}
+		body := secret[len(prefix) : len(prefix)+30]
+		checksum := secret[len(prefix)+30 : len(prefix)+36]
+		if string(checksum) == base62(crc32.ChecksumIEEE(body), 6) {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+func base62(n uint32, width int) string {
+	out := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		out[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(out)
+}
@@ -0,0 +1,168 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// jwtProvider is the provider whose findings JWTFilter decodes.
+const jwtProvider = "GENERIC_JWT"
+
+// JWTFilter decodes the header and claims of JWT findings offline. Findings
+// that are not structurally valid JWTs are suppressed. Valid ones are
+// annotated with their algorithm, issuer, audience and expiry, and expired or
+// unsigned tokens have their severity lowered. Signatures are not verified.
+type JWTFilter struct {
+	now func() time.Time
+}
+
+// NewJWTFilter returns a JWTFilter.
+func NewJWTFilter() *JWTFilter {
+	return &JWTFilter{now: time.Now}
+}
+
+// Name implements findings.Filter.
+func (j *JWTFilter) Name() string {
+	return "jwt"
+}
+
+// Filter implements findings.Filter.
+func (j *JWTFilter) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	kept := found[:0]
+	for _, f := range found {
+		if f.Provider != jwtProvider {
+			kept = append(kept, f)
+			continue
+		}
+		token, err := decodeJWT(f.Secret)
+		if err != nil {
+			f.Decide(j.Name(), findings.OutcomeSuppress, err.Error())
+			continue
+		}
+		j.annotate(f, token)
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+func (j *JWTFilter) annotate(f *findings.Finding, token *jwtToken) {
+	f.Annotate("jwt.alg", token.Header.Alg)
+	if token.Claims.Iss != "" {
+		f.Annotate("jwt.iss", token.Claims.Iss)
+	}
+	if len(token.Claims.Aud) > 0 {
+		f.Annotate("jwt.aud", strings.Join(token.Claims.Aud, ","))
+	}
+	if token.Claims.Exp != nil {
+		f.Annotate("jwt.exp", token.Claims.Exp.UTC().Format(time.RFC3339))
+	}
+
+	switch {
+	case token.unsigned():
+		f.Severity = f.Severity.Lower()
+		f.Decide(j.Name(), findings.OutcomeFlag, "unsigned token")
+	case token.Claims.Exp != nil && token.Claims.Exp.Before(j.now()):
+		f.Severity = f.Severity.Lower()
+		f.Decide(j.Name(), findings.OutcomeFlag, "expired "+token.Claims.Exp.UTC().Format(time.RFC3339))
+	}
+}
+
+type jwtToken struct {
+	Header struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ"`
+	}
+	Claims struct {
+		Iss string      `json:"iss"`
+		Aud jwtAudience `json:"aud"`
+		Exp *jwtTime    `json:"exp"`
+	}
+	signature []byte
+}
+
+func (t *jwtToken) unsigned() bool {
+	return strings.EqualFold(t.Header.Alg, "none") || len(t.signature) == 0
+}
+
+// jwtAudience accepts both forms of the aud claim: a single string or an
+// array of strings.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud is neither a string nor an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// jwtTime is a NumericDate: seconds since the epoch, possibly fractional.
+type jwtTime struct {
+	time.Time
+}
+
+func (t *jwtTime) UnmarshalJSON(data []byte) error {
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return errors.New("exp is not a number")
+	}
+	whole := int64(seconds)
+	t.Time = time.Unix(whole, int64((seconds-float64(whole))*1e9))
+	return nil
+}
+
+// decodeJWT decodes a compact serialized JWS without verifying it. It fails
+// unless the header and claims are base64url encoded JSON objects and the
+// header names an algorithm.
+func decodeJWT(raw []byte) (*jwtToken, error) {
+	parts := bytes.Split(bytes.TrimSpace(raw), []byte{'.'})
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token has %d segments, want 3", len(parts))
+	}
+	var token jwtToken
+	if err := decodeJWTSegment(parts[0], &token.Header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if token.Header.Alg == "" {
+		return nil, errors.New("header: no alg")
+	}
+	if err := decodeJWTSegment(parts[1], &token.Claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(string(parts[2]))
+	if err != nil {
+		return nil, errors.New("signature: not base64url")
+	}
+	token.signature = signature
+	return &token, nil
+}
+
+func decodeJWTSegment(segment []byte, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(string(segment))
+	if err != nil {
+		return errors.New("not base64url")
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return errors.New("not a JSON object")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
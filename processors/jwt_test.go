@@ -0,0 +1,17 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestJWTFilterIgnoresOtherProviders(t *testing.T) {
+	t.Parallel()
+	f := &findings.Finding{Provider: "GRAFANA_PROJECT_API_KEY", Secret: []byte("eyJrIjoiNTViZThiNjA4ZThiM2Q4MWUyZjc0MmZhNjhmNjcxZmNkMjA2ZmU2YiIsIm4iOiJhIiwiaWQiOjQ3Mzk3NX0==")}
+	require.Len(t, NewJWTFilter().Filter(context.Background(), nil, []*findings.Finding{f}), 1)
+	require.Empty(t, f.Decisions)
+}
@@ -0,0 +1,134 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// PlaceholderFilter flags findings whose secret is a published example value,
+// contains a placeholder word such as EXAMPLE, or is a single-line token
+// containing a long repeated or sequential run of characters. Flagged findings
+// are marked LikelyTest and kept, so consumers decide how to treat them.
+type PlaceholderFilter struct {
+	// examples maps a known value to the providers it applies to; an empty
+	// provider applies to all.
+	examples         map[string][]string
+	words            [][]byte
+	minRepeatedRun   int
+	minSequentialRun int
+}
+
+// NewPlaceholderFilter returns a PlaceholderFilter recognizing examples. A
+// zero run length disables that check.
+func NewPlaceholderFilter(examples *config.KnownExamples) *PlaceholderFilter {
+	f := &PlaceholderFilter{
+		examples:         make(map[string][]string, len(examples.Examples)),
+		minRepeatedRun:   examples.MinRepeatedRun,
+		minSequentialRun: examples.MinSequentialRun,
+	}
+	for _, example := range examples.Examples {
+		f.examples[example.Value] = append(f.examples[example.Value], example.Provider)
+	}
+	for _, word := range examples.Words {
+		f.words = append(f.words, []byte(strings.ToLower(word)))
+	}
+	return f
+}
+
+// Name implements findings.Filter.
+func (f *PlaceholderFilter) Name() string {
+	return "placeholder"
+}
+
+// Filter implements findings.Filter.
+func (f *PlaceholderFilter) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	for _, finding := range found {
+		if reason := f.placeholderReason(finding); reason != "" {
+			finding.LikelyTest = true
+			finding.Decide(f.Name(), findings.OutcomeFlag, reason)
+		}
+	}
+	return found
+}
+
+// placeholderReason explains why the finding looks like a placeholder, or
+// returns "" if it does not.
+func (f *PlaceholderFilter) placeholderReason(finding *findings.Finding) string {
+	secret := findings.NormalizeSecret(finding.Secret)
+	if providers, ok := f.examples[string(secret)]; ok {
+		for _, provider := range providers {
+			if provider == "" || provider == finding.Provider {
+				return "known example value"
+			}
+		}
+	}
+	lower := bytes.ToLower(secret)
+	for _, word := range f.words {
+		if bytes.Contains(lower, word) {
+			return "contains " + strings.ToUpper(string(word))
+		}
+	}
+	// Encoded key material, such as the zero padded length fields of an
+	// OpenSSH key, has long runs by chance, so the run heuristics only apply to
+	// single-line tokens.
+	if bytes.IndexByte(secret, '\n') >= 0 || looksLikePrivateKey(secret) {
+		return ""
+	}
+	if f.minRepeatedRun > 0 && longestRepeatedRun(secret) >= f.minRepeatedRun {
+		return "repeated characters"
+	}
+	if f.minSequentialRun > 0 && longestSequentialRun(lower) >= f.minSequentialRun {
+		return "sequential characters"
+	}
+	return ""
+}
+
+func longestRepeatedRun(s []byte) int {
+	longest, run := 0, 0
+	for i := range s {
+		if i > 0 && s[i] == s[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// longestSequentialRun returns the longest run of alphanumerics that each
+// differ from the previous by one in the same direction, e.g. "abcd" or
+// "4321".
+func longestSequentialRun(s []byte) int {
+	longest, run, step := 0, 0, 0
+	for i := range s {
+		if !isAlphanumeric(s[i]) {
+			run, step = 0, 0
+			continue
+		}
+		if run == 0 {
+			run = 1
+		} else if d := int(s[i]) - int(s[i-1]); (d == 1 || d == -1) && (step == 0 || d == step) && isAlphanumeric(s[i-1]) {
+			run++
+			step = d
+		} else if d == 1 || d == -1 {
+			run, step = 2, d
+		} else {
+			run, step = 1, 0
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func isAlphanumeric(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
@@ -0,0 +1,89 @@
+package processors
+
+import (
+	"context"
+	"sort"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// PrecedenceFilter is a findings.Filter that resolves overlapping findings of
+// providers with declared precedence: it suppresses a finding overlapped by a
+// kept finding of a provider superseding its own, and records it on that
+// finding as an alternative. Findings are resolved from the highest ranked
+// provider down, so the outcome does not depend on the order of found.
+// Overlapping findings of providers without declared precedence are all kept.
+type PrecedenceFilter struct {
+	precedence *config.Precedence
+}
+
+// NewPrecedenceFilter returns a PrecedenceFilter applying precedence.
+func NewPrecedenceFilter(precedence *config.Precedence) *PrecedenceFilter {
+	return &PrecedenceFilter{precedence: precedence}
+}
+
+// Name implements findings.Filter.
+func (p *PrecedenceFilter) Name() string {
+	return "precedence"
+}
+
+// Filter implements findings.Filter.
+func (p *PrecedenceFilter) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	if p.precedence.Empty() || len(found) < 2 {
+		return found
+	}
+	ordered := append([]*findings.Finding(nil), found...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if ra, rb := p.precedence.Rank(a.Provider), p.precedence.Rank(b.Provider); ra != rb {
+			return ra < rb
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End > b.End
+		}
+		return a.Provider < b.Provider
+	})
+
+	suppressed := make(map[*findings.Finding]bool)
+	var kept []*findings.Finding
+	for _, f := range ordered {
+		winner := p.superseding(f, kept)
+		if winner == nil {
+			kept = append(kept, f)
+			continue
+		}
+		suppressed[f] = true
+		f.Decide(p.Name(), findings.OutcomeSuppress, "superseded by "+winner.Provider)
+		winner.Alternatives = append(winner.Alternatives, findings.Alternative{
+			Provider:    f.Provider,
+			Start:       f.Start,
+			End:         f.End,
+			Fingerprint: f.Fingerprint,
+		})
+	}
+	if len(suppressed) == 0 {
+		return found
+	}
+	result := make([]*findings.Finding, 0, len(found)-len(suppressed))
+	for _, f := range found {
+		if !suppressed[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// superseding returns the first of kept that overlaps f and whose provider
+// supersedes f's, or nil.
+func (p *PrecedenceFilter) superseding(f *findings.Finding, kept []*findings.Finding) *findings.Finding {
+	for _, k := range kept {
+		if k.Start < f.End && f.Start < k.End && p.precedence.Relation(k.Provider, f.Provider) == config.RelationSupersedes {
+			return k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package processors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestPrecedenceFilter(t *testing.T) {
+	t.Parallel()
+	precedence, err := config.NewPrecedence([]*config.PrecedenceRule{
+		{Provider: "NPM_TOKEN_V2", Supersedes: []string{"NPM_TOKEN"}},
+		{Provider: "GENERIC_TOKEN", SupersededBy: []string{"NPM_TOKEN"}, Coexists: []string{"NPM_NAME_PRESENCE"}},
+	}, providerConfigs("NPM_TOKEN_V2", "NPM_TOKEN", "GENERIC_TOKEN", "NPM_NAME_PRESENCE"))
+	require.NoError(t, err)
+	require.Equal(t, config.RelationSupersedes, precedence.Relation("NPM_TOKEN_V2", "GENERIC_TOKEN"), "precedence is transitive")
+	require.Equal(t, config.RelationSupersededBy, precedence.Relation("GENERIC_TOKEN", "NPM_TOKEN"))
+	require.Equal(t, config.RelationCoexists, precedence.Relation("NPM_NAME_PRESENCE", "GENERIC_TOKEN"))
+	require.Equal(t, config.RelationNone, precedence.Relation("NPM_TOKEN", "NPM_NAME_PRESENCE"))
+
+	newFindings := func() []*findings.Finding {
+		// Listed lowest precedence first, so the filter cannot rely on order.
+		return []*findings.Finding{
+			{Provider: "NPM_NAME_PRESENCE", Start: 0, End: 3},
+			{Provider: "GENERIC_TOKEN", Start: 0, End: 40},
+			{Provider: "NPM_TOKEN", Start: 0, End: 40, Fingerprint: "fp-npm"},
+			{Provider: "NPM_TOKEN_V2", Start: 0, End: 40},
+			{Provider: "GENERIC_TOKEN", Start: 50, End: 90},
+		}
+	}
+	filter := NewPrecedenceFilter(precedence)
+	found := newFindings()
+	kept := filter.Filter(context.Background(), nil, found)
+	require.Equal(t, []*findings.Finding{found[0], found[3], found[4]}, kept)
+	require.ElementsMatch(t, []findings.Alternative{
+		{Provider: "GENERIC_TOKEN", Start: 0, End: 40},
+		{Provider: "NPM_TOKEN", Start: 0, End: 40, Fingerprint: "fp-npm"},
+	}, found[3].Alternatives)
+	require.Equal(t, []findings.Decision{{Filter: "precedence", Outcome: findings.OutcomeSuppress, Reason: "superseded by NPM_TOKEN_V2"}}, found[2].Decisions)
+	require.Empty(t, found[0].Alternatives)
+
+	reversed := newFindings()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	kept = filter.Filter(context.Background(), nil, reversed)
+	require.Len(t, kept, 3)
+	require.Equal(t, "NPM_TOKEN_V2", kept[1].Provider)
+	require.ElementsMatch(t, found[3].Alternatives, kept[1].Alternatives)
+}
+
+// providerConfigs returns providers with the given names.
+func providerConfigs(names ...string) []*config.ProviderConfig {
+	providers := make([]*config.ProviderConfig, len(names))
+	for i, name := range names {
+		providers[i] = &config.ProviderConfig{Name: name}
+	}
+	return providers
+}
+
+func TestPrecedenceRejectsContradictions(t *testing.T) {
+	t.Parallel()
+	for name, rules := range map[string][]*config.PrecedenceRule{
+		"self": {{Provider: "A", Supersedes: []string{"A"}}},
+		"cycle": {
+			{Provider: "A", Supersedes: []string{"B"}},
+			{Provider: "B", Supersedes: []string{"C"}},
+			{Provider: "C", Supersedes: []string{"A"}},
+		},
+		"contradiction": {
+			{Provider: "A", Supersedes: []string{"B"}},
+			{Provider: "A", SupersededBy: []string{"B"}},
+		},
+		"coexisting and superseding": {
+			{Provider: "A", Supersedes: []string{"B"}},
+			{Provider: "C", SupersededBy: []string{"B"}, Coexists: []string{"A"}},
+		},
+	} {
+		_, err := config.NewPrecedence(rules, providerConfigs("A", "B", "C"))
+		require.Error(t, err, name)
+	}
+
+	_, err := config.NewPrecedence([]*config.PrecedenceRule{
+		{Provider: "A", Supersedes: []string{"B"}},
+		{Provider: "B", SupersededBy: []string{"A"}},
+	}, providerConfigs("A", "B"))
+	require.NoError(t, err, "a relation may be declared from both sides")
+	_, err = config.NewPrecedence([]*config.PrecedenceRule{{Provider: "A", Coexists: []string{"B_TYPO"}}}, providerConfigs("A", "B"))
+	require.ErrorContains(t, err, "precedence of A refers to unknown provider B_TYPO")
+
+	_, err = config.ParsePrecedenceRules([]byte("precedence:\n  - provider: A\n    supersedes: [B]\n  - provider: B\n    supersedes: [A]\n"))
+	require.ErrorContains(t, err, "precedence cycle")
+
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`providers:
+  - name: CYCLE_A_TOKEN
+    pattern: 'cya_[a-z0-9]{16}'
+    precedence:
+      supersedes: [CYCLE_B_TOKEN]
+  - name: CYCLE_B_TOKEN
+    pattern: 'cyb_[a-z0-9]{16}'
+    precedence:
+      supersedes: [CYCLE_A_TOKEN]
+`), 0o600))
+	_, _, err = config.LoadConfigPath(path)
+	require.ErrorContains(t, err, "precedence cycle: CYCLE_A_TOKEN supersedes CYCLE_B_TOKEN supersedes CYCLE_A_TOKEN")
+
+	require.NoError(t, os.WriteFile(path, []byte(`providers:
+  - name: CYCLE_A_TOKEN
+    pattern: 'cya_[a-z0-9]{16}'
+    metadata:
+      vendor: Example
+      name: Example Token
+      severity: low
+      environment: live
+    precedence:
+      supersedes: [CYCLE_C_TOKEN]
+`), 0o600))
+	_, _, err = config.LoadConfigPath(path)
+	require.ErrorContains(t, err, "precedence of CYCLE_A_TOKEN refers to unknown provider CYCLE_C_TOKEN")
+}
@@ -0,0 +1,368 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// PrivateKeyFilter parses findings containing PEM, OpenSSH or PuTTY private
+// keys, whichever provider matched them. Parsed keys are annotated with their
+// format, type, size and public key fingerprint. Encrypted keys and blocks
+// that cannot be parsed, for example because they were truncated, are kept
+// with a lowered severity.
+type PrivateKeyFilter struct{}
+
+// NewPrivateKeyFilter returns a PrivateKeyFilter.
+func NewPrivateKeyFilter() *PrivateKeyFilter {
+	return &PrivateKeyFilter{}
+}
+
+// Name implements findings.Filter.
+func (p *PrivateKeyFilter) Name() string {
+	return "privatekey"
+}
+
+// Filter implements findings.Filter.
+func (p *PrivateKeyFilter) Filter(_ context.Context, _ *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	for _, f := range found {
+		if !looksLikePrivateKey(f.Secret) {
+			continue
+		}
+		key, err := parsePrivateKey(f.Secret)
+		if err != nil {
+			f.Severity = f.Severity.Lower()
+			f.Decide(p.Name(), findings.OutcomeFlag, "unparseable key: "+err.Error())
+			continue
+		}
+		key.annotate(f)
+		if key.encrypted {
+			f.Severity = f.Severity.Lower()
+			f.Decide(p.Name(), findings.OutcomeFlag, "encrypted key")
+		}
+	}
+	return found
+}
+
+const puttyHeader = "PuTTY-User-Key-File-"
+
+func looksLikePrivateKey(secret []byte) bool {
+	if bytes.HasPrefix(secret, []byte(puttyHeader)) {
+		return true
+	}
+	header, _, _ := bytes.Cut(secret, []byte{'\n'})
+	return bytes.HasPrefix(header, []byte("-----BEGIN ")) && bytes.Contains(header, []byte("PRIVATE KEY"))
+}
+
+// privateKey is what could be learned about a private key without its
+// passphrase.
+type privateKey struct {
+	format    string
+	keyType   string
+	bits      int
+	encrypted bool
+	// fingerprint is the OpenSSH style SHA256 fingerprint of the public key.
+	fingerprint string
+}
+
+func (k *privateKey) annotate(f *findings.Finding) {
+	f.Annotate("key.format", k.format)
+	if k.keyType != "" {
+		f.Annotate("key.type", k.keyType)
+	}
+	if k.bits > 0 {
+		f.Annotate("key.bits", strconv.Itoa(k.bits))
+	}
+	f.Annotate("key.encrypted", strconv.FormatBool(k.encrypted))
+	if k.fingerprint != "" {
+		f.Annotate("key.fingerprint", k.fingerprint)
+	}
+}
+
+func parsePrivateKey(data []byte) (*privateKey, error) {
+	if bytes.HasPrefix(data, []byte(puttyHeader)) {
+		return parsePuTTYKey(data)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no complete PEM block")
+	}
+	// RFC 1421 encryption, used by "openssl rsa -des3" and friends.
+	legacyEncrypted := strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSHKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return &privateKey{format: "pkcs8", encrypted: true}, nil
+	case "RSA PRIVATE KEY":
+		if legacyEncrypted {
+			return &privateKey{format: "pkcs1", keyType: "rsa", encrypted: true}, nil
+		}
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newPrivateKey("pkcs1", &key.PublicKey)
+	case "EC PRIVATE KEY":
+		if legacyEncrypted {
+			return &privateKey{format: "sec1", keyType: "ecdsa", encrypted: true}, nil
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newPrivateKey("sec1", &key.PublicKey)
+	case "DSA PRIVATE KEY":
+		if legacyEncrypted {
+			return &privateKey{format: "dsa", keyType: "dsa", encrypted: true}, nil
+		}
+		return parseDSAKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return newPrivateKey("pkcs8", &key.PublicKey)
+		case *ecdsa.PrivateKey:
+			return newPrivateKey("pkcs8", &key.PublicKey)
+		case ed25519.PrivateKey:
+			return newPrivateKey("pkcs8", key.Public())
+		default:
+			return nil, fmt.Errorf("unsupported PKCS #8 key %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// newPrivateKey describes a parsed, unencrypted key from its public half.
+func newPrivateKey(format string, public interface{}) (*privateKey, error) {
+	blob, err := sshPublicKeyBlob(public)
+	if err != nil {
+		return nil, err
+	}
+	key, err := describeSSHPublicKey(blob)
+	if err != nil {
+		return nil, err
+	}
+	key.format = format
+	return key, nil
+}
+
+type dsaPrivateKey struct {
+	Version       int
+	P, Q, G, Y, X *big.Int
+}
+
+func parseDSAKey(der []byte) (*privateKey, error) {
+	var key dsaPrivateKey
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after DSA key")
+	}
+	var blob sshWriter
+	blob.string([]byte("ssh-dss"))
+	for _, n := range []*big.Int{key.P, key.Q, key.G, key.Y} {
+		blob.mpint(n)
+	}
+	return &privateKey{format: "dsa", keyType: "dsa", bits: key.P.BitLen(), fingerprint: sshFingerprint(blob)}, nil
+}
+
+// parseOpenSSHKey parses the openssh-key-v1 format. The public key is stored
+// in the clear, so encrypted keys can still be fingerprinted.
+func parseOpenSSHKey(data []byte) (*privateKey, error) {
+	const magic = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("not an openssh-key-v1 key")
+	}
+	r := newSSHReader(data[len(magic):])
+	cipher := r.string()
+	r.string() // kdf name
+	r.string() // kdf options
+	count := r.uint32()
+	public := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if count != 1 {
+		return nil, fmt.Errorf("file holds %d keys, want 1", count)
+	}
+	key, err := describeSSHPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	key.format = "openssh"
+	key.encrypted = string(cipher) != "none"
+	return key, nil
+}
+
+// parsePuTTYKey parses the headers and public lines of a PuTTY .ppk file.
+func parsePuTTYKey(data []byte) (*privateKey, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	key := &privateKey{format: "putty"}
+	for i := 0; i < len(lines); i++ {
+		name, value, ok := strings.Cut(lines[i], ": ")
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "Encryption":
+			key.encrypted = value != "none"
+		case name == "Public-Lines":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || i+n >= len(lines) {
+				return nil, errors.New("truncated public key")
+			}
+			blob, err := base64.StdEncoding.DecodeString(strings.Join(lines[i+1:i+1+n], ""))
+			if err != nil {
+				return nil, fmt.Errorf("public key: %w", err)
+			}
+			public, err := describeSSHPublicKey(blob)
+			if err != nil {
+				return nil, err
+			}
+			key.keyType, key.bits, key.fingerprint = public.keyType, public.bits, public.fingerprint
+			return key, nil
+		}
+	}
+	return nil, errors.New("no public key")
+}
+
+// describeSSHPublicKey reads the type and size of an SSH wire format public
+// key and fingerprints it.
+func describeSSHPublicKey(blob []byte) (*privateKey, error) {
+	r := newSSHReader(blob)
+	algorithm := string(r.string())
+	key := &privateKey{fingerprint: sshFingerprint(blob)}
+	switch algorithm {
+	case "ssh-rsa":
+		r.string() // e
+		key.keyType, key.bits = "rsa", new(big.Int).SetBytes(r.string()).BitLen()
+	case "ssh-dss":
+		key.keyType, key.bits = "dsa", new(big.Int).SetBytes(r.string()).BitLen()
+	case "ssh-ed25519":
+		if len(r.string()) != ed25519.PublicKeySize && r.err == nil {
+			return nil, errors.New("public key: bad ed25519 key length")
+		}
+		key.keyType, key.bits = "ed25519", 256
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		r.string() // curve
+		if len(r.string()) == 0 && r.err == nil {
+			return nil, errors.New("public key: empty ecdsa point")
+		}
+		bits, _ := strconv.Atoi(strings.TrimPrefix(algorithm, "ecdsa-sha2-nistp"))
+		key.keyType, key.bits = "ecdsa", bits
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("public key: %w", r.err)
+	}
+	return key, nil
+}
+
+// sshPublicKeyBlob encodes a public key in the SSH wire format (RFC 4253
+// section 6.6, RFC 5656 and RFC 8709).
+func sshPublicKeyBlob(public interface{}) ([]byte, error) {
+	var w sshWriter
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		w.string([]byte("ssh-rsa"))
+		w.mpint(big.NewInt(int64(public.E)))
+		w.mpint(public.N)
+	case *ecdsa.PublicKey:
+		curve := "nistp" + strconv.Itoa(public.Curve.Params().BitSize)
+		point, err := public.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		w.string([]byte("ecdsa-sha2-" + curve))
+		w.string([]byte(curve))
+		w.string(point.Bytes())
+	case ed25519.PublicKey:
+		w.string([]byte("ssh-ed25519"))
+		w.string(public)
+	default:
+		return nil, fmt.Errorf("unsupported public key %T", public)
+	}
+	return w, nil
+}
+
+func sshFingerprint(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+type sshWriter []byte
+
+func (w *sshWriter) string(s []byte) {
+	*w = binary.BigEndian.AppendUint32(*w, uint32(len(s)))
+	*w = append(*w, s...)
+}
+
+func (w *sshWriter) mpint(n *big.Int) {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	w.string(b)
+}
+
+// sshReader reads SSH wire format values, remembering the first error so
+// callers can check once after a sequence of reads.
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func newSSHReader(data []byte) *sshReader {
+	return &sshReader{data: data}
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) < 4 {
+		r.err = errors.New("truncated")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.data)) < uint64(n) {
+		r.err = errors.New("truncated")
+		return nil
+	}
+	s := r.data[:n]
+	r.data = r.data[n:]
+	return s
+}
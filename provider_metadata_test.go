@@ -0,0 +1,58 @@
+package hypercredscan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+func TestAllHyperscanProvidersHaveMetadata(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, prodConfig.ValidateMetadata())
+}
+
+func TestShippedPrecedenceNamesKnownProviders(t *testing.T) {
+	t.Parallel()
+	_, err := prodConfig.Precedence()
+	require.NoError(t, err)
+}
+
+func TestProviderMetadata(t *testing.T) {
+	t.Parallel()
+	metadata := func(name string) *config.ProviderMetadata {
+		m := (&config.ProviderConfig{Name: name}).Metadata()
+		require.NotNil(t, m, name)
+		return m
+	}
+	require.Greater(t, metadata("PAYPAL_CLIENT_SECRET").Severity.Rank(), metadata("DEVCYCLE_CLIENT_API_KEY").Severity.Rank())
+	require.Equal(t, config.EnvironmentTest, metadata("STRIPE_TEST_API_SECRET_KEY").Environment)
+	require.Equal(t, config.EnvironmentTest, metadata("EASYPOST_TEST_API_KEY").Environment)
+	require.Equal(t, config.EnvironmentSandbox, metadata("EBAY_SANDBOX_CLIENT_SECRET").Environment)
+	require.Equal(t, config.EnvironmentLive, metadata("STRIPE_LIVE_API_SECRET_KEY").Environment)
+	require.Equal(t, config.SeverityInfo, metadata("PACKAGE_LOCK_INTEGRITY").Severity)
+	require.Equal(t, "Stripe", metadata("STRIPE_LIVE_API_SECRET_KEY").Vendor)
+	require.Nil(t, (&config.ProviderConfig{Name: "NOT_A_PROVIDER"}).Metadata())
+
+	require.Equal(t, config.SeverityHigh, config.SeverityCritical.Lower())
+	require.Equal(t, config.SeverityInfo, config.SeverityInfo.Lower())
+
+	cfg, err := config.LoadCustomConfig([]*config.ProviderConfig{{Name: "NOT_A_PROVIDER", Pattern: "x"}})
+	require.NoError(t, err)
+	require.ErrorContains(t, cfg.ValidateMetadata(), "NOT_A_PROVIDER: no metadata")
+	_, err = NewScanner(cfg)
+	require.Error(t, err)
+	_, err = config.LoadValidatedCustomConfig([]*config.ProviderConfig{{Name: "NOT_A_PROVIDER", Pattern: "x"}})
+	require.ErrorContains(t, err, "NOT_A_PROVIDER: no metadata")
+	_, err = config.LoadValidatedDefaultConfig()
+	require.NoError(t, err)
+}
+
+func TestParseProviderMetadataRequiresFields(t *testing.T) {
+	t.Parallel()
+	_, err := config.ParseProviderMetadata([]byte("providers:\n  CUSTOM:\n    vendor: Acme\n    severity: high\n    environment: live\n"))
+	require.ErrorContains(t, err, "missing name")
+	_, err = config.ParseProviderMetadata([]byte("providers:\n  CUSTOM:\n    vendor: Acme\n    name: Acme Key\n    severity: severe\n    environment: live\n"))
+	require.ErrorContains(t, err, "unknown severity")
+}
@@ -0,0 +1,59 @@
+package hypercredscan
+
+import (
+	"context"
+	"io"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// RedactingWriter forwards everything written to it to an underlying writer
+// with detected secrets replaced. Input is forwarded a line at a time: a line
+// is held back until its line break is written, so a token split across
+// several Write calls is still redacted, but a token spanning lines must be
+// written whole. It is safe for concurrent use, e.g. as the output of a
+// logger, as long as each Write carries whole log entries.
+type RedactingWriter struct {
+	stream *StreamScanner
+}
+
+// NewRedactingWriter returns a RedactingWriter that scans with scanner and
+// writes to w. A nil mask defaults to ProviderHashMask.
+func NewRedactingWriter(w io.Writer, scanner *Scanner, mask Masker) *RedactingWriter {
+	if mask == nil {
+		mask = ProviderHashMask
+	}
+	return &RedactingWriter{
+		stream: NewStreamScanner(context.Background(), scanner, nil, StreamOptions{Echo: w, Mask: mask}),
+	}
+}
+
+// Write implements io.Writer. Every complete line buffered once p is added is
+// scanned and written to the underlying writer before Write returns, and any
+// error doing so is returned; a trailing partial line waits for the rest of
+// the line, Flush or Close.
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	return r.stream.writeLines(p)
+}
+
+// Flush writes out all buffered input. It must not be called while a token may
+// be only partially written.
+func (r *RedactingWriter) Flush() error {
+	return r.stream.Flush()
+}
+
+// Close flushes buffered input. It does not close the underlying writer.
+func (r *RedactingWriter) Close() error {
+	return r.stream.Close()
+}
+
+// ProviderHashMask replaces a secret with its provider name and the start of
+// its fingerprint, e.g. "[REDACTED:GITHUB_PAT:1a2b3c4d]", so repeated
+// occurrences of the same secret can be correlated without revealing it.
+func ProviderHashMask(f *findings.Finding) []byte {
+	fingerprint := f.Fingerprint
+	if len(fingerprint) > 8 {
+		fingerprint = fingerprint[:8]
+	}
+	return []byte("[REDACTED:" + f.Provider + ":" + fingerprint + "]")
+}
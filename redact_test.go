@@ -0,0 +1,91 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	t.Parallel()
+	const token = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+	scanner, err := NewScanner(getConfig("ADAFRUIT_AIO_KEY"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	t.Run("token split across writes", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		w := NewRedactingWriter(&out, scanner, nil)
+		for _, part := range []string{"before ", token[:10], token[10:20], token[20:], " after\n"} {
+			n, err := w.Write([]byte(part))
+			require.NoError(t, err)
+			require.Equal(t, len(part), n)
+		}
+		require.NoError(t, w.Close())
+		mask := ProviderHashMask(&findings.Finding{
+			Provider:    "ADAFRUIT_AIO_KEY",
+			Fingerprint: findings.NewFingerprinter(nil).Fingerprint("ADAFRUIT_AIO_KEY", []byte(token)),
+		})
+		require.Regexp(t, `^\[REDACTED:ADAFRUIT_AIO_KEY:[0-9a-f]{8}\]$`, string(mask))
+		require.Equal(t, "before "+string(mask)+" after\n", out.String())
+	})
+
+	t.Run("complete lines are forwarded immediately", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		w := NewRedactingWriter(&out, scanner, MaskPreservingLines)
+		_, err := w.Write([]byte("first " + token + "\nsecond " + token[:10]))
+		require.NoError(t, err)
+		masked := strings.Repeat("*", len(token))
+		require.Equal(t, "first "+masked+"\n", out.String())
+		_, err = w.Write([]byte(token[10:] + "\n"))
+		require.NoError(t, err)
+		require.Equal(t, "first "+masked+"\nsecond "+masked+"\n", out.String())
+		require.NoError(t, w.Close())
+	})
+
+	t.Run("overlapping tokens", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		w := NewRedactingWriter(&out, overlappingScanner(t), MaskPreservingLines)
+		_, err := w.Write([]byte("x tok_abc\ndef\nghi_end y\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.Equal(t, "x *******\n***\n******* y\n", out.String())
+	})
+
+	t.Run("concurrent log lines", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		w := NewRedactingWriter(&out, scanner, MaskPreservingLines)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := fmt.Fprintf(w, "worker %d using %s\n", i, token)
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+
+		require.NotContains(t, out.String(), token)
+		require.Equal(t, 50, strings.Count(out.String(), "\n"))
+		require.Equal(t, 50, strings.Count(out.String(), strings.Repeat("*", len(token))))
+	})
+}
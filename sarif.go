@@ -0,0 +1,272 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://docs.oasis-open.org/sarif/sarif/v2.1.0/errata01/os/schemas/sarif-schema-2.1.0.json"
+	sarifToolName = "hypercredscan"
+	// sarifFingerprintKey names the partial fingerprint used to correlate the
+	// same secret across runs.
+	sarifFingerprintKey = "secretFingerprint/v1"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	FullDescription      *sarifMessage          `json:"fullDescription,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifRuleConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	RuleIndex           int                    `json:"ruleIndex"`
+	Level               string                 `json:"level"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints"`
+	Rank                float64                `json:"rank"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn"`
+	EndLine     int           `json:"endLine"`
+	EndColumn   int           `json:"endColumn"`
+	ByteOffset  uint64        `json:"byteOffset"`
+	ByteLength  uint64        `json:"byteLength"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// SARIFMatchProcessor collects findings and writes them as a SARIF 2.1.0 log
+// on Flush. The log has one rule per provider in the configuration and one
+// result per finding; secrets appear only redacted.
+type SARIFMatchProcessor struct {
+	w io.Writer
+
+	mu        sync.Mutex
+	rules     []sarifRule
+	ruleIndex map[string]int
+	results   []sarifResult
+}
+
+// NewSARIFMatchProcessor returns a SARIFMatchProcessor writing to w, with rules
+// for every provider in cfg.
+func NewSARIFMatchProcessor(w io.Writer, cfg *config.Config) findings.Processor {
+	p := &SARIFMatchProcessor{w: w, ruleIndex: make(map[string]int)}
+	for _, provider := range cfg.HyperscanProviders() {
+		if _, ok := p.ruleIndex[provider.Name]; ok {
+			continue
+		}
+		p.ruleIndex[provider.Name] = len(p.rules)
+		p.rules = append(p.rules, newSARIFRule(provider))
+	}
+	return p
+}
+
+// sarifSecuritySeverity maps severities to the CVSS-style scores code
+// scanning tools read from the security-severity rule property.
+var sarifSecuritySeverity = map[config.Severity]string{
+	config.SeverityCritical: "9.5",
+	config.SeverityHigh:     "8.0",
+	config.SeverityMedium:   "5.5",
+	config.SeverityLow:      "2.0",
+	config.SeverityInfo:     "0.0",
+}
+
+// sarifLevel returns the SARIF level for findings of severity. Findings
+// without a severity are reported as errors.
+func sarifLevel(severity config.Severity) string {
+	switch severity {
+	case config.SeverityMedium:
+		return "warning"
+	case config.SeverityLow, config.SeverityInfo:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func newSARIFRule(provider *config.ProviderConfig) sarifRule {
+	rule := sarifRule{
+		ID:                   provider.Name,
+		Name:                 provider.Name,
+		ShortDescription:     sarifMessage{Text: "Secret of type " + provider.Name},
+		DefaultConfiguration: sarifRuleConfiguration{Level: "error"},
+		Properties: map[string]interface{}{
+			"tags": []string{"security", "secret"},
+		},
+	}
+	metadata := provider.Metadata()
+	if metadata == nil {
+		return rule
+	}
+	rule.ShortDescription.Text = metadata.Name
+	if metadata.Revocation != "" {
+		rule.FullDescription = &sarifMessage{Text: metadata.Revocation}
+	}
+	rule.HelpURI = metadata.DocsURL
+	rule.DefaultConfiguration.Level = sarifLevel(metadata.Severity)
+	rule.Properties["security-severity"] = sarifSecuritySeverity[metadata.Severity]
+	rule.Properties["severity"] = metadata.Severity
+	rule.Properties["vendor"] = metadata.Vendor
+	rule.Properties["environment"] = metadata.Environment
+	return rule
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *SARIFMatchProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	result := newSARIFResult(f)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	index, ok := p.ruleIndex[f.Provider]
+	if !ok {
+		index = len(p.rules)
+		p.ruleIndex[f.Provider] = index
+		p.rules = append(p.rules, newSARIFRule(&config.ProviderConfig{Name: f.Provider}))
+	}
+	result.RuleIndex = index
+	p.results = append(p.results, result)
+	return nil
+}
+
+func newSARIFResult(f *findings.Finding) sarifResult {
+	endLine, endColumn := f.Line, f.Column+len(f.Secret)
+	if n := bytes.Count(f.Secret, []byte{'\n'}); n > 0 {
+		endLine += n
+		endColumn = len(f.Secret) - bytes.LastIndexByte(f.Secret, '\n')
+	}
+	region := sarifRegion{
+		StartLine:   f.Line,
+		StartColumn: f.Column,
+		EndLine:     endLine,
+		EndColumn:   endColumn,
+		ByteOffset:  f.Start,
+		ByteLength:  f.End - f.Start,
+	}
+	if f.Snippet != "" {
+		region.Snippet = &sarifMessage{Text: f.Snippet}
+	}
+
+	result := sarifResult{
+		RuleID:  f.Provider,
+		Level:   sarifLevel(f.Severity),
+		Message: sarifMessage{Text: "Possible " + f.Provider + " secret: " + f.RedactedSecret()},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: (&url.URL{Path: f.Path}).String()},
+				Region:           region,
+			},
+		}},
+		PartialFingerprints: map[string]string{
+			sarifFingerprintKey: f.Fingerprint,
+		},
+		Rank: f.Confidence * 100,
+		Properties: map[string]interface{}{
+			"confidence": f.Confidence,
+		},
+	}
+	if f.Severity != "" {
+		result.Properties["severity"] = f.Severity
+	}
+	if len(f.Annotations) > 0 {
+		result.Properties["annotations"] = f.Annotations
+	}
+	if f.Validation != nil {
+		result.Properties["validation"] = f.Validation.Status
+	}
+	if f.ConfigVersion != "" {
+		result.Properties["configVersion"] = f.ConfigVersion
+		result.Properties["patternHash"] = f.PatternHash
+	}
+	// Likely test values are still reported, but at a level most consumers
+	// do not fail on.
+	if f.LikelyTest {
+		result.Level = "note"
+		result.Properties["likelyTest"] = true
+	}
+	return result
+}
+
+// Flush implements findings.Processor.
+func (p *SARIFMatchProcessor) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sort.SliceStable(p.results, func(i, j int) bool {
+		a, b := p.results[i].Locations[0].PhysicalLocation, p.results[j].Locations[0].PhysicalLocation
+		if a.ArtifactLocation.URI != b.ArtifactLocation.URI {
+			return a.ArtifactLocation.URI < b.ArtifactLocation.URI
+		}
+		return a.Region.ByteOffset < b.Region.ByteOffset
+	})
+	results := p.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: sarifToolName, Rules: p.rules}},
+			Results: results,
+		}},
+	})
+}
@@ -0,0 +1,90 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+//go:embed testdata/sarif-schema-2.1.0.json
+var sarifSchemaJSON []byte
+
+func validateSARIF(t *testing.T, log []byte) {
+	t.Helper()
+	schemaDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(sarifSchemaJSON))
+	require.NoError(t, err)
+	compiler := jsonschema.NewCompiler()
+	require.NoError(t, compiler.AddResource(sarifSchema, schemaDoc))
+	schema, err := compiler.Compile(sarifSchema)
+	require.NoError(t, err)
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(log))
+	require.NoError(t, err)
+	require.NoError(t, schema.Validate(doc))
+}
+
+func TestSARIFMatchProcessor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	processor := NewSARIFMatchProcessor(&out, prodConfig)
+
+	content := []byte("config:\n  key: aio_FMBo07xPM4e0Aj3eYjO23blItBvS\n")
+	scanner, err := NewScanner(getConfig("ADAFRUIT_AIO_KEY"))
+	require.NoError(t, err)
+	defer scanner.Close()
+	found, err := scanner.Scan(ctx, &findings.Blob{Path: "dir with space/app.yml", Content: content})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	for _, f := range found {
+		require.NoError(t, processor.ProcessFinding(ctx, f))
+	}
+	require.NoError(t, processor.Flush())
+
+	validateSARIF(t, out.Bytes())
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, len(prodConfig.HyperscanProviders()))
+	require.Len(t, run.Results, 1)
+
+	result := run.Results[0]
+	require.Equal(t, "ADAFRUIT_AIO_KEY", result.RuleID)
+	rule := run.Tool.Driver.Rules[result.RuleIndex]
+	require.Equal(t, "ADAFRUIT_AIO_KEY", rule.ID)
+	require.Equal(t, "Adafruit AIO Key", rule.ShortDescription.Text)
+	require.Equal(t, "8.0", rule.Properties["security-severity"])
+	require.Equal(t, "error", result.Level)
+	require.NotEmpty(t, result.PartialFingerprints[sarifFingerprintKey])
+
+	location := result.Locations[0].PhysicalLocation
+	require.Equal(t, "dir%20with%20space/app.yml", location.ArtifactLocation.URI)
+	require.Equal(t, sarifRegion{
+		StartLine:   2,
+		StartColumn: 8,
+		EndLine:     2,
+		EndColumn:   40,
+		ByteOffset:  15,
+		ByteLength:  32,
+		Snippet:     &sarifMessage{Text: "  key: aio_****************************"},
+	}, location.Region)
+	require.NotContains(t, out.String(), "aio_FMBo07xPM4e0Aj3eYjO23blItBvS")
+}
+
+func TestSARIFMatchProcessorEmpty(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	processor := NewSARIFMatchProcessor(&out, prodConfig)
+	require.NoError(t, processor.Flush())
+	validateSARIF(t, out.Bytes())
+}
@@ -0,0 +1,431 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" // nolint: gosec
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/flier/gohs/hyperscan"
+	"github.com/github/go-stats"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/processors"
+)
+
+// Scanner runs a compiled provider database over in-memory blobs and turns
+// raw Hyperscan matches into findings. A Scanner is safe for concurrent use;
+// each scan borrows a scratch space from an internal pool.
+type Scanner struct {
+	cfg           *config.Config
+	declarations  *config.Declarations
+	db            hyperscan.BlockDatabase
+	providers     []*config.ProviderConfig
+	suppressions  map[string]*config.Suppression
+	precedence    *config.Precedence
+	metadata      map[string]*config.ProviderMetadata
+	version       string
+	patternHashes map[string]string
+	fingerprinter *findings.Fingerprinter
+	logger        Logger
+	reporter      ExceptionReporter
+	statter       stats.Client
+
+	mu        sync.Mutex
+	prototype *hyperscan.Scratch
+	scratches []*hyperscan.Scratch
+	closed    bool
+}
+
+// ScannerOption customizes a Scanner.
+type ScannerOption func(*Scanner)
+
+// WithFingerprintKey sets the key used to fingerprint secrets. Without it
+// findings.DefaultFingerprintKey is used.
+func WithFingerprintKey(key []byte) ScannerOption {
+	return func(s *Scanner) {
+		s.fingerprinter = findings.NewFingerprinter(key)
+	}
+}
+
+// WithDeclarations makes the scanner take its providers' metadata,
+// precedence and suppression from declarations, such as those loaded with
+// config.LoadConfigPath, before the shipped ones.
+func WithDeclarations(declarations *config.Declarations) ScannerOption {
+	return func(s *Scanner) {
+		s.declarations = declarations
+	}
+}
+
+// WithStatter sets the client the scanner's stats are sent to, each tagged
+// with the scanner's configuration version. Without it stats are discarded.
+func WithStatter(statter stats.Client) ScannerOption {
+	return func(s *Scanner) {
+		s.statter = statter
+	}
+}
+
+// NewScanner compiles the database for cfg and allocates the first scratch
+// space. Every provider in cfg must have metadata.
+func NewScanner(cfg *config.Config, opts ...ScannerOption) (*Scanner, error) {
+	s := &Scanner{
+		cfg:           cfg,
+		providers:     cfg.HyperscanProviders(),
+		suppressions:  make(map[string]*config.Suppression),
+		metadata:      make(map[string]*config.ProviderMetadata),
+		patternHashes: cfg.PatternHashes(),
+		fingerprinter: findings.NewFingerprinter(nil),
+		logger:        NewSysLogger(""),
+		reporter:      NewEmptyExceptionReporter(),
+		statter:       stats.NullStatter,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.declarations.ValidateMetadata(cfg); err != nil {
+		return nil, err
+	}
+	precedence, err := s.declarations.Precedence(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.precedence = precedence
+	s.version = s.declarations.Version(cfg)
+	s.statter = &versionedStatter{Client: s.statter, version: s.version}
+	db, err := cfg.Database()
+	if err != nil {
+		return nil, fmt.Errorf("compiling provider database: %w", err)
+	}
+	scratch, err := hyperscan.NewScratch(db)
+	if err != nil {
+		return nil, fmt.Errorf("allocating scratch: %w", err)
+	}
+	s.db = db
+	s.prototype = scratch
+	for _, provider := range s.providers {
+		s.metadata[provider.Name] = s.declarations.Metadata(provider)
+		if suppression := s.declarations.Suppression(provider); suppression != nil {
+			s.suppressions[provider.Name] = suppression
+		}
+	}
+	return s, nil
+}
+
+// Fingerprint returns the fingerprint the scanner assigns to secret when found
+// by provider, so systems holding raw secrets, such as revocation services,
+// can match findings without the scanner storing plaintext.
+func (s *Scanner) Fingerprint(provider string, secret []byte) string {
+	return s.fingerprinter.Fingerprint(provider, secret)
+}
+
+// Config returns the configuration the scanner was built from.
+func (s *Scanner) Config() *config.Config {
+	return s.cfg
+}
+
+// Declarations returns the declarations the scanner was built with, or nil.
+func (s *Scanner) Declarations() *config.Declarations {
+	return s.declarations
+}
+
+// Precedence returns the precedence of the scanner's providers, which decides
+// between their overlapping matches.
+func (s *Scanner) Precedence() *config.Precedence {
+	return s.precedence
+}
+
+// ConfigVersion returns the version of the configuration the scanner was
+// built from, which is stamped on every finding.
+func (s *Scanner) ConfigVersion() string {
+	return s.version
+}
+
+// Close frees all scratch spaces held by the scanner. Scans must not be in
+// flight when Close is called.
+func (s *Scanner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for _, scratch := range s.scratches {
+		if err := scratch.Free(); err != nil {
+			return err
+		}
+	}
+	s.scratches = nil
+	return s.prototype.Free()
+}
+
+func (s *Scanner) acquireScratch() (*hyperscan.Scratch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("scanner is closed")
+	}
+	if n := len(s.scratches); n > 0 {
+		scratch := s.scratches[n-1]
+		s.scratches = s.scratches[:n-1]
+		return scratch, nil
+	}
+	return s.prototype.Clone()
+}
+
+func (s *Scanner) releaseScratch(scratch *hyperscan.Scratch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		_ = scratch.Free()
+		return
+	}
+	s.scratches = append(s.scratches, scratch)
+}
+
+// Scan returns the findings in blob, ordered by offset. Matches go through
+// NewScanCallback with the chain of newFilterChain, so a token is reported
+// once per provider however many accepting offsets Hyperscan finds for it.
+// Overlapping matches of providers with declared precedence are resolved by
+// it rather than by the chain's alternative match filter: the superseded
+// match is dropped and recorded as an alternative of the one kept. Matches of
+// suppressor providers are not returned; they cancel the matches of other
+// providers in their region instead.
+func (s *Scanner) Scan(ctx context.Context, blob *findings.Blob) ([]*findings.Finding, error) {
+	if blob.SHA == "" {
+		blob.SHA = BlobSHA(blob.Content)
+	}
+	result, err := s.scanContent(ctx, blob.SHA, blob.Content)
+	if err != nil {
+		return nil, fmt.Errorf("scanning blob %s: %w", blob.SHA, err)
+	}
+	for _, f := range result {
+		f.BlobSHA = blob.SHA
+		f.Path = blob.Path
+		f.Line, f.Column = findings.Locate(blob.Content, f.Start)
+		f.Snippet = findings.Snippet(f, blob.Content, result)
+	}
+	return result, nil
+}
+
+// scanContent returns the provider, offsets, secret and fingerprint of every
+// match in content; locating the matches within their blob is left to the
+// caller.
+func (s *Scanner) scanContent(ctx context.Context, sha string, content []byte) ([]*findings.Finding, error) {
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	scratch, err := s.acquireScratch()
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseScratch(scratch)
+
+	// Every match goes through the full chain and, to resolve precedence,
+	// through the chain without the alternative match filter as well.
+	collector := &findingCollector{scanner: s, content: content}
+	callback := NewScanCallback(ctx, s.logger, s.reporter, s.cfg, newFilterChain(), collector)
+	var alternatives *findingCollector
+	if !s.precedence.Empty() {
+		alternatives = &findingCollector{scanner: s, content: content}
+		filtered := callback
+		unresolved := NewScanCallback(ctx, s.logger, s.reporter, s.cfg, newLengthFilterChain(), alternatives)
+		callback = func(providerIdx uint, sha string, content []byte, match []byte, from, to uint64, blobContext *BlobContext, statter stats.Client) error {
+			if err := filtered(providerIdx, sha, content, match, from, to, blobContext, statter); err != nil {
+				return err
+			}
+			return unresolved(providerIdx, sha, content, match, from, to, blobContext, statter)
+		}
+	}
+	dbs := []*DatabaseWithCallback{{Database: s.db, Callback: callback}}
+	// Archives are expanded by ScanPaths, which scans each member as a blob of
+	// its own.
+	if err := ScanWithScratchV2(ctx, s.logger, s.reporter, s.statter, &contentBlob{sha: sha, content: content}, dbs, scratch, false); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	result := collector.found
+	if alternatives != nil {
+		result = s.resolvePrecedence(ctx, result, alternatives.found)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Start != result[j].Start {
+			return result[i].Start < result[j].Start
+		}
+		return result[i].End > result[j].End
+	})
+	return suppressOverlapping(content, result, s.suppressions), nil
+}
+
+// newFilterChain returns the filters NewScanCallback applies to raw matches
+// before they reach the match processor. It is the minimal chain the provider
+// integration tests use, which drops the shorter spans Hyperscan reports for a
+// variable length token. It is not the token scanning service's production
+// chain, which is not part of this package; callers filter findings further
+// with a findings.Filter.
+func newFilterChain() processors.Filter {
+	return processors.NewChainFilter(processors.LengthFilter(0), processors.ExactLengthFilter(), processors.AlternativeMatchFilter())
+}
+
+// newLengthFilterChain is newFilterChain without the alternative match
+// filter, so every provider matching a span is reported.
+func newLengthFilterChain() processors.Filter {
+	return processors.NewChainFilter(processors.LengthFilter(0), processors.ExactLengthFilter())
+}
+
+// resolvePrecedence replaces the alternative match filter's choice between
+// providers with declared precedence. unresolved are the matches of the same
+// scan before that filter, and kept those after it. Declared precedence
+// decides between unresolved; a match it keeps is returned if the filter kept
+// it too, or if every other provider matching the same span has a declared
+// relation to its own, so only the filter's choices between undeclared
+// providers stand.
+func (s *Scanner) resolvePrecedence(ctx context.Context, kept, unresolved []*findings.Finding) []*findings.Finding {
+	type span struct {
+		provider   string
+		start, end uint64
+	}
+	filtered := make(map[span]bool, len(kept))
+	for _, f := range kept {
+		filtered[span{f.Provider, f.Start, f.End}] = true
+	}
+	resolved := processors.NewPrecedenceFilter(s.precedence).Filter(ctx, nil, unresolved)
+	result := make([]*findings.Finding, 0, len(resolved))
+	for _, f := range resolved {
+		if filtered[span{f.Provider, f.Start, f.End}] || s.declaredRivals(f, unresolved) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// declaredRivals reports whether every other provider matching the span of f
+// in found has a declared relation to the provider of f.
+func (s *Scanner) declaredRivals(f *findings.Finding, found []*findings.Finding) bool {
+	for _, other := range found {
+		if other.Provider != f.Provider && other.Start == f.Start && other.End == f.End &&
+			s.precedence.Relation(f.Provider, other.Provider) == config.RelationNone {
+			return false
+		}
+	}
+	return true
+}
+
+// findingCollector is the match processor a scan hands to NewScanCallback. It
+// turns every match that passes the filter chain into a finding, fingerprinted
+// as it is delivered, so no consumer of the scanner sees a match without one.
+type findingCollector struct {
+	scanner *Scanner
+	content []byte
+	found   []*findings.Finding
+}
+
+// ProcessMatch implements MatchProcessor.
+func (c *findingCollector) ProcessMatch(_ context.Context, m *Match) error {
+	metadata, ok := c.scanner.metadata[m.ProviderName]
+	if !ok {
+		return fmt.Errorf("match for unknown provider %s", m.ProviderName)
+	}
+	c.found = append(c.found, &findings.Finding{
+		Provider:      m.ProviderName,
+		Start:         m.Start,
+		End:           m.End,
+		Secret:        c.content[m.Start:m.End],
+		Metadata:      metadata,
+		Severity:      metadata.Severity,
+		Fingerprint:   c.scanner.fingerprinter.Fingerprint(m.ProviderName, c.content[m.Start:m.End]),
+		ConfigVersion: c.scanner.version,
+		PatternHash:   c.scanner.patternHashes[m.ProviderName],
+	})
+	return nil
+}
+
+// versionedStatter adds the version of the scanner's configuration to the
+// tags of every counter, so stats can be told apart across reloads.
+type versionedStatter struct {
+	stats.Client
+	version string
+}
+
+func (v *versionedStatter) Counter(name string, tags stats.Tags, value int64) {
+	tagged := make(stats.Tags, len(tags)+1)
+	for key, value := range tags {
+		tagged[key] = value
+	}
+	tagged["config_version"] = v.version
+	v.Client.Counter(name, tagged, value)
+}
+
+// contentBlob is the Blob ScanWithScratchV2 reads scanned content from.
+type contentBlob struct {
+	sha     string
+	content []byte
+}
+
+func (b *contentBlob) SHA() string {
+	return b.sha
+}
+
+func (b *contentBlob) Content() []byte {
+	return b.content
+}
+
+// suppressOverlapping drops suppressor matches along with every match from
+// another provider that overlaps a suppressor's region.
+func suppressOverlapping(content []byte, matches []*findings.Finding, suppressions map[string]*config.Suppression) []*findings.Finding {
+	type region struct{ start, end uint64 }
+	var regions []region
+	for _, m := range matches {
+		suppression, ok := suppressions[m.Provider]
+		if !ok {
+			continue
+		}
+		r := region{start: m.Start, end: m.End}
+		if suppression.Region == config.SuppressLine {
+			if i := bytes.IndexByte(content[m.End:], '\n'); i >= 0 {
+				r.end = m.End + uint64(i)
+			} else {
+				r.end = uint64(len(content))
+			}
+		}
+		regions = append(regions, r)
+	}
+	if len(regions) == 0 {
+		return matches
+	}
+
+	kept := matches[:0]
+	for _, m := range matches {
+		if _, ok := suppressions[m.Provider]; ok {
+			continue
+		}
+		suppressed := false
+		for _, r := range regions {
+			if m.Start < r.end && r.start < m.End {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// BlobSHA returns the git blob SHA of content, so findings from files on disk
+// carry the same identifier as findings from pushed objects.
+func BlobSHA(content []byte) string {
+	h := sha1.New() // nolint: gosec
+	h.Write([]byte("blob " + strconv.Itoa(len(content)) + "\x00"))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,35 @@
+package scanrpc
+
+//go:generate protoc --proto_path=.. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative scanrpc/scan.proto
+
+import (
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// newFinding converts a scanner finding for the wire. It never carries the
+// secret itself.
+func newFinding(f *findings.Finding) *Finding {
+	metadata := f.ProviderMetadata()
+	return &Finding{
+		Provider:     f.Provider,
+		Path:         f.Path,
+		Start:        f.Start,
+		End:          f.End,
+		Line:         int32(f.Line),
+		Column:       int32(f.Column),
+		Redacted:     f.RedactedSecret(),
+		Fingerprint:  f.Fingerprint,
+		Severity:     string(f.Severity),
+		Vendor:       metadata.Vendor,
+		ProviderName: metadata.Name,
+		Confidence:   f.Confidence,
+		LikelyTest:   f.LikelyTest,
+		Annotations:  f.Annotations,
+		BlobSha:      f.BlobSHA,
+
+		ConfigVersion: f.ConfigVersion,
+		PatternHash:   f.PatternHash,
+		Environment:   string(metadata.Environment),
+		Revocation:    metadata.Revocation,
+	}
+}
@@ -0,0 +1,57 @@
+package scanrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+func TestNewFinding(t *testing.T) {
+	t.Parallel()
+	finding := newFinding(&findings.Finding{
+		Provider:      "GITHUB_PERSONAL_ACCESS_TOKEN",
+		BlobSHA:       "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Path:          "config/app.yml",
+		ConfigVersion: "5d41402abc4b2a76",
+		PatternHash:   "b9ea4cf6a6c3d0e1",
+		Metadata: &config.ProviderMetadata{
+			Name:        "GitHub Personal Access Token",
+			Vendor:      "GitHub",
+			Environment: config.EnvironmentLive,
+			Revocation:  "Delete the token in your developer settings.",
+		},
+		Severity:    config.SeverityHigh,
+		Start:       15,
+		End:         55,
+		Line:        2,
+		Column:      8,
+		Secret:      []byte("ghp_wxyz"),
+		Fingerprint: "0f1e",
+		LikelyTest:  true,
+		Confidence:  0.85,
+		Annotations: map[string]string{"jwt.alg": "HS256"},
+	})
+	got, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(finding)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"provider": "GITHUB_PERSONAL_ACCESS_TOKEN", "path": "config/app.yml", "start": "15", "end": "55",
+		"line": 2, "column": 8, "redacted": "********", "fingerprint": "0f1e", "severity": "high",
+		"vendor": "GitHub", "provider_name": "GitHub Personal Access Token", "confidence": 0.85,
+		"likely_test": true, "annotations": {"jwt.alg": "HS256"},
+		"blob_sha": "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		"config_version": "5d41402abc4b2a76", "pattern_hash": "b9ea4cf6a6c3d0e1",
+		"environment": "live", "revocation": "Delete the token in your developer settings."
+	}`, string(got))
+	require.NotContains(t, string(got), "wxyz", "the secret is never sent")
+
+	encoded, err := proto.Marshal(&ScanResponse{Findings: []*Finding{finding}})
+	require.NoError(t, err)
+	var decoded ScanResponse
+	require.NoError(t, proto.Unmarshal(encoded, &decoded))
+	require.True(t, proto.Equal(finding, decoded.Findings[0]))
+}
@@ -0,0 +1,441 @@
+// The Scanner service scans content for credentials. The Go code in
+// scan.pb.go and scan_grpc.pb.go is generated from this definition; run
+// go generate in this package after changing it.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: scanrpc/scan.proto
+
+package scanrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ScanRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Path    string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Content []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	// Who the scan is for, selecting the providers enabled for them. Like the
+	// path, a stream takes them from its first chunk.
+	Tenant        string `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Repository    string `protobuf:"bytes,4,opt,name=repository,proto3" json:"repository,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_scanrpc_scan_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scanrpc_scan_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_scanrpc_scan_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ScanRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+type ScanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Findings      []*Finding             `protobuf:"bytes,1,rep,name=findings,proto3" json:"findings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
+	mi := &file_scanrpc_scan_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResponse) ProtoMessage() {}
+
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_scanrpc_scan_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return file_scanrpc_scan_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ScanResponse) GetFindings() []*Finding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
+// Finding is a detected secret. The secret itself is never sent, only its
+// redacted form and fingerprint.
+type Finding struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Provider string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Path     string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// Byte offsets of the secret within the blob.
+	Start uint64 `protobuf:"varint,3,opt,name=start,proto3" json:"start,omitempty"`
+	End   uint64 `protobuf:"varint,4,opt,name=end,proto3" json:"end,omitempty"`
+	// 1-based line and column of the first byte of the secret.
+	Line         int32             `protobuf:"varint,5,opt,name=line,proto3" json:"line,omitempty"`
+	Column       int32             `protobuf:"varint,6,opt,name=column,proto3" json:"column,omitempty"`
+	Redacted     string            `protobuf:"bytes,7,opt,name=redacted,proto3" json:"redacted,omitempty"`
+	Fingerprint  string            `protobuf:"bytes,8,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Severity     string            `protobuf:"bytes,9,opt,name=severity,proto3" json:"severity,omitempty"`
+	Vendor       string            `protobuf:"bytes,10,opt,name=vendor,proto3" json:"vendor,omitempty"`
+	ProviderName string            `protobuf:"bytes,11,opt,name=provider_name,json=providerName,proto3" json:"provider_name,omitempty"`
+	Confidence   float64           `protobuf:"fixed64,12,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	LikelyTest   bool              `protobuf:"varint,13,opt,name=likely_test,json=likelyTest,proto3" json:"likely_test,omitempty"`
+	Annotations  map[string]string `protobuf:"bytes,14,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	BlobSha      string            `protobuf:"bytes,15,opt,name=blob_sha,json=blobSha,proto3" json:"blob_sha,omitempty"`
+	// The version of the provider configuration and the hash of the provider
+	// pattern that produced the finding.
+	ConfigVersion string `protobuf:"bytes,16,opt,name=config_version,json=configVersion,proto3" json:"config_version,omitempty"`
+	PatternHash   string `protobuf:"bytes,17,opt,name=pattern_hash,json=patternHash,proto3" json:"pattern_hash,omitempty"`
+	// The kind of account the credential belongs to, such as live or test, and
+	// how its owner revokes it.
+	Environment   string `protobuf:"bytes,18,opt,name=environment,proto3" json:"environment,omitempty"`
+	Revocation    string `protobuf:"bytes,19,opt,name=revocation,proto3" json:"revocation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Finding) Reset() {
+	*x = Finding{}
+	mi := &file_scanrpc_scan_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Finding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Finding) ProtoMessage() {}
+
+func (x *Finding) ProtoReflect() protoreflect.Message {
+	mi := &file_scanrpc_scan_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Finding.ProtoReflect.Descriptor instead.
+func (*Finding) Descriptor() ([]byte, []int) {
+	return file_scanrpc_scan_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Finding) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Finding) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Finding) GetStart() uint64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Finding) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Finding) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Finding) GetColumn() int32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *Finding) GetRedacted() string {
+	if x != nil {
+		return x.Redacted
+	}
+	return ""
+}
+
+func (x *Finding) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Finding) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Finding) GetVendor() string {
+	if x != nil {
+		return x.Vendor
+	}
+	return ""
+}
+
+func (x *Finding) GetProviderName() string {
+	if x != nil {
+		return x.ProviderName
+	}
+	return ""
+}
+
+func (x *Finding) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Finding) GetLikelyTest() bool {
+	if x != nil {
+		return x.LikelyTest
+	}
+	return false
+}
+
+func (x *Finding) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Finding) GetBlobSha() string {
+	if x != nil {
+		return x.BlobSha
+	}
+	return ""
+}
+
+func (x *Finding) GetConfigVersion() string {
+	if x != nil {
+		return x.ConfigVersion
+	}
+	return ""
+}
+
+func (x *Finding) GetPatternHash() string {
+	if x != nil {
+		return x.PatternHash
+	}
+	return ""
+}
+
+func (x *Finding) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *Finding) GetRevocation() string {
+	if x != nil {
+		return x.Revocation
+	}
+	return ""
+}
+
+var File_scanrpc_scan_proto protoreflect.FileDescriptor
+
+const file_scanrpc_scan_proto_rawDesc = "" +
+	"\n" +
+	"\x12scanrpc/scan.proto\x12\x10hypercredscan.v1\"s\n" +
+	"\vScanRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12\x16\n" +
+	"\x06tenant\x18\x03 \x01(\tR\x06tenant\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x04 \x01(\tR\n" +
+	"repository\"E\n" +
+	"\fScanResponse\x125\n" +
+	"\bfindings\x18\x01 \x03(\v2\x19.hypercredscan.v1.FindingR\bfindings\"\x9a\x05\n" +
+	"\aFinding\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x14\n" +
+	"\x05start\x18\x03 \x01(\x04R\x05start\x12\x10\n" +
+	"\x03end\x18\x04 \x01(\x04R\x03end\x12\x12\n" +
+	"\x04line\x18\x05 \x01(\x05R\x04line\x12\x16\n" +
+	"\x06column\x18\x06 \x01(\x05R\x06column\x12\x1a\n" +
+	"\bredacted\x18\a \x01(\tR\bredacted\x12 \n" +
+	"\vfingerprint\x18\b \x01(\tR\vfingerprint\x12\x1a\n" +
+	"\bseverity\x18\t \x01(\tR\bseverity\x12\x16\n" +
+	"\x06vendor\x18\n" +
+	" \x01(\tR\x06vendor\x12#\n" +
+	"\rprovider_name\x18\v \x01(\tR\fproviderName\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\f \x01(\x01R\n" +
+	"confidence\x12\x1f\n" +
+	"\vlikely_test\x18\r \x01(\bR\n" +
+	"likelyTest\x12L\n" +
+	"\vannotations\x18\x0e \x03(\v2*.hypercredscan.v1.Finding.AnnotationsEntryR\vannotations\x12\x19\n" +
+	"\bblob_sha\x18\x0f \x01(\tR\ablobSha\x12%\n" +
+	"\x0econfig_version\x18\x10 \x01(\tR\rconfigVersion\x12!\n" +
+	"\fpattern_hash\x18\x11 \x01(\tR\vpatternHash\x12 \n" +
+	"\venvironment\x18\x12 \x01(\tR\venvironment\x12\x1e\n" +
+	"\n" +
+	"revocation\x18\x13 \x01(\tR\n" +
+	"revocation\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\x9c\x01\n" +
+	"\aScanner\x12E\n" +
+	"\x04Scan\x12\x1d.hypercredscan.v1.ScanRequest\x1a\x1e.hypercredscan.v1.ScanResponse\x12J\n" +
+	"\n" +
+	"ScanStream\x12\x1d.hypercredscan.v1.ScanRequest\x1a\x19.hypercredscan.v1.Finding(\x010\x01BNZLgithub.com/github/token-scanning-service/hypercredscan/hypercredscan/scanrpcb\x06proto3"
+
+var (
+	file_scanrpc_scan_proto_rawDescOnce sync.Once
+	file_scanrpc_scan_proto_rawDescData []byte
+)
+
+func file_scanrpc_scan_proto_rawDescGZIP() []byte {
+	file_scanrpc_scan_proto_rawDescOnce.Do(func() {
+		file_scanrpc_scan_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_scanrpc_scan_proto_rawDesc), len(file_scanrpc_scan_proto_rawDesc)))
+	})
+	return file_scanrpc_scan_proto_rawDescData
+}
+
+var file_scanrpc_scan_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_scanrpc_scan_proto_goTypes = []any{
+	(*ScanRequest)(nil),  // 0: hypercredscan.v1.ScanRequest
+	(*ScanResponse)(nil), // 1: hypercredscan.v1.ScanResponse
+	(*Finding)(nil),      // 2: hypercredscan.v1.Finding
+	nil,                  // 3: hypercredscan.v1.Finding.AnnotationsEntry
+}
+var file_scanrpc_scan_proto_depIdxs = []int32{
+	2, // 0: hypercredscan.v1.ScanResponse.findings:type_name -> hypercredscan.v1.Finding
+	3, // 1: hypercredscan.v1.Finding.annotations:type_name -> hypercredscan.v1.Finding.AnnotationsEntry
+	0, // 2: hypercredscan.v1.Scanner.Scan:input_type -> hypercredscan.v1.ScanRequest
+	0, // 3: hypercredscan.v1.Scanner.ScanStream:input_type -> hypercredscan.v1.ScanRequest
+	1, // 4: hypercredscan.v1.Scanner.Scan:output_type -> hypercredscan.v1.ScanResponse
+	2, // 5: hypercredscan.v1.Scanner.ScanStream:output_type -> hypercredscan.v1.Finding
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_scanrpc_scan_proto_init() }
+func file_scanrpc_scan_proto_init() {
+	if File_scanrpc_scan_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_scanrpc_scan_proto_rawDesc), len(file_scanrpc_scan_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_scanrpc_scan_proto_goTypes,
+		DependencyIndexes: file_scanrpc_scan_proto_depIdxs,
+		MessageInfos:      file_scanrpc_scan_proto_msgTypes,
+	}.Build()
+	File_scanrpc_scan_proto = out.File
+	file_scanrpc_scan_proto_goTypes = nil
+	file_scanrpc_scan_proto_depIdxs = nil
+}
@@ -0,0 +1,217 @@
+// Package scanrpc serves the Scanner gRPC service defined in scan.proto, for
+// callers that scan at high volume or scan blobs too large to send at once.
+package scanrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/github/go-stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// Stats recorded by the service, tagged by method.
+const (
+	requestsStat = "hypercredscan.grpc.requests"
+	bytesStat    = "hypercredscan.grpc.bytes"
+	findingsStat = "hypercredscan.grpc.findings"
+	errorsStat   = "hypercredscan.grpc.errors"
+)
+
+// loaded holds the source the service scans with, swapped as a whole by
+// LoadSource.
+type loaded struct {
+	source hypercredscan.ScannerSource
+}
+
+// Service implements ScannerServer. Like the HTTP server it refuses scans
+// until Load or LoadSource has given it a scanner.
+type Service struct {
+	UnimplementedScannerServer
+
+	statter stats.Client
+	loaded  atomic.Pointer[loaded]
+}
+
+// NewService returns a Service with no scanner loaded, recording stats with
+// statter.
+func NewService(statter stats.Client) *Service {
+	if statter == nil {
+		statter = stats.NullStatter
+	}
+	return &Service{statter: statter}
+}
+
+// Load makes the service scan with scanner, filtering findings with filter if
+// it is not nil. Calls already in flight finish with what they started with.
+func (s *Service) Load(scanner *hypercredscan.Scanner, filter findings.Filter) {
+	s.LoadSource(hypercredscan.StaticSource(scanner, filter))
+}
+
+// LoadSource makes the service lease a scanner from source for every call. A
+// streaming call keeps its lease until the stream ends.
+func (s *Service) LoadSource(source hypercredscan.ScannerSource) {
+	s.loaded.Store(&loaded{source: source})
+}
+
+// source returns the loaded source, failing if the service is not loaded.
+func (s *Service) source() (hypercredscan.ScannerSource, error) {
+	l := s.loaded.Load()
+	if l == nil {
+		return nil, status.Error(codes.Unavailable, "provider database is not loaded yet")
+	}
+	return l.source, nil
+}
+
+// acquire leases a scanner from source for who req is for.
+func acquire(source hypercredscan.ScannerSource, req *ScanRequest) (*hypercredscan.Lease, error) {
+	lease, err := source.Acquire(hypercredscan.Identity{Tenant: req.Tenant, Repository: req.Repository})
+	switch {
+	case errors.Is(err, hypercredscan.ErrNoProvidersEnabled):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return lease, nil
+}
+
+// Scan implements ScannerServer.
+func (s *Service) Scan(ctx context.Context, req *ScanRequest) (*ScanResponse, error) {
+	tags := stats.Tags{"method": "Scan"}
+	s.statter.Counter(requestsStat, tags, 1)
+	source, err := s.source()
+	if err != nil {
+		return nil, s.fail(tags, err)
+	}
+	lease, err := acquire(source, req)
+	if err != nil {
+		return nil, s.fail(tags, err)
+	}
+	defer lease.Release()
+	tags = versionTags(tags, lease)
+	s.statter.Counter(bytesStat, tags, int64(len(req.Content)))
+
+	collector := &collector{}
+	blob := &findings.Blob{Path: req.Path, Content: req.Content}
+	if err := hypercredscan.ScanBlob(ctx, lease.Scanner, blob, lease.Filter, collector); err != nil {
+		return nil, s.fail(tags, err)
+	}
+	s.statter.Counter(findingsStat, tags, int64(len(collector.findings)))
+	return &ScanResponse{Findings: collector.findings}, nil
+}
+
+// ScanStream implements ScannerServer. Chunks are fed through a
+// hypercredscan.StreamScanner, so tokens spanning chunks are found, and
+// findings are sent as soon as the window holding them is settled.
+func (s *Service) ScanStream(stream Scanner_ScanStreamServer) error {
+	tags := stats.Tags{"method": "ScanStream"}
+	s.statter.Counter(requestsStat, tags, 1)
+	source, err := s.source()
+	if err != nil {
+		return s.fail(tags, err)
+	}
+	// The lease depends on who the first chunk says the scan is for.
+	req, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return s.fail(tags, err)
+	}
+	lease, err := acquire(source, req)
+	if err != nil {
+		return s.fail(tags, err)
+	}
+	defer lease.Release()
+	tags = versionTags(tags, lease)
+	sender := &sender{stream: stream}
+	scanner := hypercredscan.NewStreamScanner(stream.Context(), lease.Scanner, sender, hypercredscan.StreamOptions{
+		Path:   req.Path,
+		Filter: lease.Filter,
+	})
+	var received int64
+	for {
+		received += int64(len(req.Content))
+		if _, err := scanner.Write(req.Content); err != nil {
+			return s.fail(tags, err)
+		}
+		req, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return s.fail(tags, err)
+		}
+	}
+	if err := scanner.Close(); err != nil {
+		return s.fail(tags, err)
+	}
+	s.statter.Counter(bytesStat, tags, received)
+	s.statter.Counter(findingsStat, tags, sender.sent)
+	return nil
+}
+
+// versionTags returns tags with the version of the leased configuration
+// added, so stats can be told apart across reloads.
+func versionTags(tags stats.Tags, lease *hypercredscan.Lease) stats.Tags {
+	tagged := make(stats.Tags, len(tags)+1)
+	for key, value := range tags {
+		tagged[key] = value
+	}
+	tagged["config_version"] = lease.Scanner.ConfigVersion()
+	return tagged
+}
+
+// fail counts err and converts it to a gRPC status.
+func (s *Service) fail(tags stats.Tags, err error) error {
+	s.statter.Counter(errorsStat, tags, 1)
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// collector gathers the findings of a unary scan.
+type collector struct {
+	mu       sync.Mutex
+	findings []*Finding
+}
+
+func (c *collector) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.findings = append(c.findings, newFinding(f))
+	return nil
+}
+
+func (c *collector) Flush() error {
+	return nil
+}
+
+// sender sends findings of a streaming scan as they are processed.
+type sender struct {
+	mu     sync.Mutex
+	stream Scanner_ScanStreamServer
+	sent   int64
+}
+
+func (s *sender) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent++
+	return s.stream.Send(newFinding(f))
+}
+
+func (s *sender) Flush() error {
+	return nil
+}
@@ -0,0 +1,138 @@
+package scanrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/go-stats"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+)
+
+const adafruitToken = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+type countingStatter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *countingStatter) Counter(name string, tags stats.Tags, value int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name+"/"+tags["method"]] += value
+}
+
+func (c *countingStatter) get(name string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[name]
+}
+
+// startService serves a Service over an in-memory connection and returns a
+// client for it.
+func startService(t *testing.T, load bool) (ScannerClient, *countingStatter) {
+	t.Helper()
+	statter := &countingStatter{counts: make(map[string]int64)}
+	service := NewService(statter)
+	if load {
+		cfg, err := config.LoadDefaultConfig()
+		require.NoError(t, err)
+		scanner, err := hypercredscan.NewScanner(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = scanner.Close() })
+		service.Load(scanner, nil)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterScannerServer(srv, service)
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewScannerClient(conn), statter
+}
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+	client, statter := startService(t, true)
+	resp, err := client.Scan(context.Background(), &ScanRequest{
+		Path:    "config/app.yml",
+		Content: []byte("name: demo\nkey: " + adafruitToken + "\n"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Findings, 1)
+	f := resp.Findings[0]
+	require.Equal(t, "ADAFRUIT_AIO_KEY", f.Provider)
+	require.Equal(t, "config/app.yml", f.Path)
+	require.Equal(t, uint64(16), f.Start)
+	require.Equal(t, int32(2), f.Line)
+	require.Equal(t, "Adafruit", f.Vendor)
+	require.NotEmpty(t, f.Fingerprint)
+	require.NotContains(t, f.Redacted, adafruitToken)
+
+	require.Equal(t, int64(1), statter.get(requestsStat+"/Scan"))
+	require.Equal(t, int64(1), statter.get(findingsStat+"/Scan"))
+}
+
+func TestScanStream(t *testing.T) {
+	t.Parallel()
+	client, statter := startService(t, true)
+	stream, err := client.ScanStream(context.Background())
+	require.NoError(t, err)
+
+	// The first token is split across two chunks. Enough input follows it to
+	// settle the window, so its finding arrives before the stream is closed.
+	filler := strings.Repeat("log line without secrets\n", (hypercredscan.DefaultStreamChunkSize+hypercredscan.DefaultStreamOverlap)/25+1)
+	require.NoError(t, stream.Send(&ScanRequest{Path: "build.log", Content: []byte("token=" + adafruitToken[:10])}))
+	require.NoError(t, stream.Send(&ScanRequest{Content: []byte(adafruitToken[10:] + "\n" + filler)}))
+	f, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "ADAFRUIT_AIO_KEY", f.Provider)
+	require.Equal(t, "build.log", f.Path)
+	require.Equal(t, uint64(6), f.Start)
+	require.Equal(t, int32(1), f.Line)
+
+	require.NoError(t, stream.Send(&ScanRequest{Content: []byte("again " + adafruitToken)}))
+	require.NoError(t, stream.CloseSend())
+	f, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, int32(2+strings.Count(filler, "\n")), f.Line)
+	_, err = stream.Recv()
+	require.True(t, errors.Is(err, io.EOF), err)
+
+	require.Equal(t, int64(2), statter.get(findingsStat+"/ScanStream"))
+}
+
+func TestScanUnavailableUntilLoaded(t *testing.T) {
+	t.Parallel()
+	client, statter := startService(t, false)
+	_, err := client.Scan(context.Background(), &ScanRequest{Content: []byte("x")})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	stream, err := client.ScanStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, int64(1), statter.get(errorsStat+"/Scan"))
+}
@@ -0,0 +1,255 @@
+// Package server exposes a Scanner over HTTP, so callers can scan content
+// without linking Hyperscan themselves.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	// DefaultMaxRequestBytes bounds the size of a scan request body.
+	DefaultMaxRequestBytes = 10 << 20
+	// DefaultTimeout bounds how long a single scan request may take.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Options configures a Server. Zero fields take the defaults noted.
+type Options struct {
+	// MaxRequestBytes defaults to DefaultMaxRequestBytes.
+	MaxRequestBytes int64
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxConcurrent is the number of scan requests served at once; requests
+	// beyond it are rejected with 429. Defaults to the number of CPUs.
+	MaxConcurrent int
+}
+
+// loaded holds the source a Server scans with, so it can be swapped
+// atomically.
+type loaded struct {
+	source hypercredscan.ScannerSource
+}
+
+// Server serves scan requests. It answers health checks from the moment it is
+// created, but only reports ready, and only scans, once Load or LoadSource
+// has given it a scanner; compiling the provider database can take a while.
+type Server struct {
+	opts   Options
+	loaded atomic.Pointer[loaded]
+	slots  chan struct{}
+	mux    *http.ServeMux
+}
+
+// New returns a Server with no scanner loaded.
+func New(opts Options) *Server {
+	if opts.MaxRequestBytes <= 0 {
+		opts.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = runtime.NumCPU()
+	}
+	s := &Server{
+		opts:  opts,
+		slots: make(chan struct{}, opts.MaxConcurrent),
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/scan", s.handleScan)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+	return s
+}
+
+// Load makes the server scan with scanner, filtering findings with filter if
+// it is not nil. Requests already in flight finish with what they started
+// with.
+func (s *Server) Load(scanner *hypercredscan.Scanner, filter findings.Filter) {
+	s.LoadSource(hypercredscan.StaticSource(scanner, filter))
+}
+
+// LoadSource makes the server lease a scanner from source for every request,
+// such as from a hypercredscan.ConfigManager reloading its configuration.
+func (s *Server) LoadSource(source hypercredscan.ScannerSource) {
+	s.loaded.Store(&loaded{source: source})
+}
+
+// errNotLoaded is returned by acquire before the server is loaded.
+var errNotLoaded = errors.New("provider database is not loaded yet")
+
+func (s *Server) acquire(identity hypercredscan.Identity) (*hypercredscan.Lease, error) {
+	l := s.loaded.Load()
+	if l == nil {
+		return nil, errNotLoaded
+	}
+	return l.source.Acquire(identity)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+type readyResponse struct {
+	Ready          bool   `json:"ready"`
+	DatabaseLoaded bool   `json:"database_loaded"`
+	ConfigVersion  string `json:"config_version,omitempty"`
+	Providers      int    `json:"providers,omitempty"`
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	lease, err := s.acquire(hypercredscan.Identity{})
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, readyResponse{})
+		return
+	}
+	defer lease.Release()
+	cfg := lease.Scanner.Config()
+	writeJSON(w, http.StatusOK, readyResponse{
+		Ready:          true,
+		DatabaseLoaded: true,
+		ConfigVersion:  lease.Scanner.ConfigVersion(),
+		Providers:      len(cfg.HyperscanProviders()),
+	})
+}
+
+type scanResponse struct {
+	ConfigVersion string          `json:"config_version"`
+	Findings      json.RawMessage `json:"findings"`
+}
+
+// handleScan scans the request body, or each file of a multipart/form-data
+// body, and responds with the findings in the json output format. A raw
+// body's path can be given with the path query parameter, and who the scan is
+// for with the tenant and repository query parameters.
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("scan requests must be POSTed"))
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, errors.New("too many concurrent scans"))
+		return
+	}
+
+	query := r.URL.Query()
+	lease, err := s.acquire(hypercredscan.Identity{Tenant: query.Get("tenant"), Repository: query.Get("repository")})
+	switch {
+	case errors.Is(err, hypercredscan.ErrNoProvidersEnabled):
+		writeError(w, http.StatusForbidden, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer lease.Release()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+	defer cancel()
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxRequestBytes)
+
+	var out bytes.Buffer
+	processor := hypercredscan.NewJSONMatchProcessor(&out)
+	err = s.scanRequest(ctx, lease, r, processor)
+	if err == nil {
+		err = processor.Flush()
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", s.opts.MaxRequestBytes))
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, errors.New("scan timed out"))
+	case errors.Is(err, errBadRequest):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, scanResponse{ConfigVersion: lease.Scanner.ConfigVersion(), Findings: out.Bytes()})
+	}
+}
+
+// errBadRequest wraps errors caused by malformed requests.
+var errBadRequest = errors.New("bad request")
+
+func (s *Server) scanRequest(ctx context.Context, lease *hypercredscan.Lease, r *http.Request, processor findings.Processor) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		blob := &findings.Blob{Path: r.URL.Query().Get("path"), Content: content}
+		return hypercredscan.ScanBlob(ctx, lease.Scanner, blob, lease.Filter, processor)
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", errBadRequest, err)
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		blob := &findings.Blob{Path: part.FileName(), Content: content}
+		if err := hypercredscan.ScanBlob(ctx, lease.Scanner, blob, lease.Filter, processor); err != nil {
+			return err
+		}
+	}
+}
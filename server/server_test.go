@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const adafruitToken = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+type scanResult struct {
+	ConfigVersion string `json:"config_version"`
+	Findings      []struct {
+		Provider    string `json:"provider"`
+		Path        string `json:"path"`
+		Line        int    `json:"line"`
+		Redacted    string `json:"redacted"`
+		Fingerprint string `json:"fingerprint"`
+		Severity    string `json:"severity"`
+		Vendor      string `json:"vendor"`
+		Environment string `json:"environment"`
+	} `json:"findings"`
+}
+
+func newLoadedServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	cfg, err := config.LoadDefaultConfig()
+	require.NoError(t, err)
+	scanner, err := hypercredscan.NewScanner(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = scanner.Close() })
+	s := New(opts)
+	s.Load(scanner, nil)
+	return s
+}
+
+func serve(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScanRawBody(t *testing.T) {
+	t.Parallel()
+	s := newLoadedServer(t, Options{})
+	rec := serve(s, httptest.NewRequest(http.MethodPost, "/scan?path=config/app.yml", strings.NewReader("name: demo\nkey: "+adafruitToken+"\n")))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var result scanResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.NotEmpty(t, result.ConfigVersion)
+	require.Len(t, result.Findings, 1)
+	f := result.Findings[0]
+	require.Equal(t, "ADAFRUIT_AIO_KEY", f.Provider)
+	require.Equal(t, "config/app.yml", f.Path)
+	require.Equal(t, 2, f.Line)
+	require.Equal(t, "Adafruit", f.Vendor)
+	require.NotEmpty(t, f.Severity)
+	require.Equal(t, "live", f.Environment)
+	require.Equal(t, findings.NewFingerprinter(nil).Fingerprint("ADAFRUIT_AIO_KEY", []byte(adafruitToken)), f.Fingerprint)
+	require.NotContains(t, rec.Body.String(), adafruitToken)
+}
+
+func TestScanMultipart(t *testing.T) {
+	t.Parallel()
+	s := newLoadedServer(t, Options{})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("comment", adafruitToken))
+	for name, content := range map[string]string{
+		"a.txt": "nothing here",
+		"b.env": "AIO_KEY=" + adafruitToken,
+	} {
+		part, err := mw.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/scan", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := serve(s, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var result scanResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	// Form fields that are not files are not scanned.
+	require.Len(t, result.Findings, 1)
+	require.Equal(t, "b.env", result.Findings[0].Path)
+}
+
+func TestScanNoFindings(t *testing.T) {
+	t.Parallel()
+	s := newLoadedServer(t, Options{})
+	rec := serve(s, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader("nothing to see")))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"findings":[]`)
+}
+
+func TestScanLimits(t *testing.T) {
+	t.Parallel()
+	s := newLoadedServer(t, Options{MaxRequestBytes: 16, MaxConcurrent: 1})
+
+	rec := serve(s, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(strings.Repeat("x", 17))))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = serve(s, httptest.NewRequest(http.MethodGet, "/scan", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	// With the only slot taken, further requests are turned away.
+	s.slots <- struct{}{}
+	rec = serve(s, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader("x")))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	<-s.slots
+	rec = serve(s, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader("x")))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	t.Parallel()
+	s := New(Options{})
+	rec := serve(s, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"ready":false,"database_loaded":false}`, rec.Body.String())
+	rec = serve(s, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader("x")))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	lease, err := newLoadedServer(t, Options{}).acquire(hypercredscan.Identity{})
+	require.NoError(t, err)
+	s.Load(lease.Scanner, nil)
+	rec = serve(s, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ready readyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ready))
+	require.True(t, ready.Ready)
+	require.True(t, ready.DatabaseLoaded)
+	require.Equal(t, lease.Scanner.Config().Version(), ready.ConfigVersion)
+	require.Positive(t, ready.Providers)
+}
+
+func TestScanIdentity(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.LoadDefaultConfig()
+	require.NoError(t, err)
+	scanner, err := hypercredscan.NewScanner(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = scanner.Close() })
+	policy, err := config.ParsePolicy([]byte("opt_in: [ADAFRUIT_AIO_KEY]\ntenants:\n  acme:\n    enable: [ADAFRUIT_AIO_KEY]\n"))
+	require.NoError(t, err)
+	source := hypercredscan.NewPolicySource(hypercredscan.StaticSource(scanner, nil), policy, hypercredscan.PolicySourceOptions{})
+	t.Cleanup(func() { _ = source.Close() })
+	s := New(Options{})
+	s.LoadSource(source)
+
+	for target, want := range map[string]int{
+		"/scan":                                 0,
+		"/scan?tenant=acme&repository=acme/api": 1,
+	} {
+		rec := serve(s, httptest.NewRequest(http.MethodPost, target, strings.NewReader("key: "+adafruitToken)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var result scanResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		require.Len(t, result.Findings, want, target)
+	}
+}
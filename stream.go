@@ -0,0 +1,308 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+const (
+	// DefaultStreamChunkSize is how much new input StreamScanner accumulates
+	// before scanning.
+	DefaultStreamChunkSize = 64 << 10
+	// DefaultStreamOverlap is how much of each window is carried over into the
+	// next one. A token longer than the overlap can be missed when it straddles
+	// a window boundary, so this must exceed the longest token of interest;
+	// 8kb comfortably holds a PEM encoded 4096 bit RSA key.
+	DefaultStreamOverlap = 8 << 10
+)
+
+// StreamOptions configures a StreamScanner.
+type StreamOptions struct {
+	// Path is reported on every finding, e.g. "-" for stdin.
+	Path string
+	// ChunkSize defaults to DefaultStreamChunkSize.
+	ChunkSize int
+	// Overlap defaults to DefaultStreamOverlap.
+	Overlap int
+	// Echo, when set, receives a copy of the input with every detected token
+	// replaced by Mask.
+	Echo io.Writer
+	// Mask returns the replacement for a token in echoed output. Defaults to
+	// MaskPreservingLines.
+	Mask Masker
+	// Filter, if set, decides which findings are reported. Every token is
+	// masked in echoed output regardless.
+	Filter findings.Filter
+}
+
+// Masker returns the bytes that replace f's secret in redacted output.
+type Masker func(f *findings.Finding) []byte
+
+// MaskPreservingLines replaces every byte of the secret with '*' except line
+// breaks, so redacted output has the same line structure as the input.
+func MaskPreservingLines(f *findings.Finding) []byte {
+	masked := make([]byte, len(f.Secret))
+	for i, b := range f.Secret {
+		if b == '\n' || b == '\r' {
+			masked[i] = b
+		} else {
+			masked[i] = '*'
+		}
+	}
+	return masked
+}
+
+// StreamScanner scans an unbounded stream using a sliding window, holding at
+// most ChunkSize+Overlap bytes of input in memory. Findings are handed to the
+// processor, if any, as soon as the window containing them is settled, so a
+// slow producer such as a CI job sees results while it is still running.
+//
+// A StreamScanner is an io.Writer; it is safe for concurrent use, although
+// concurrent writers will interleave their input.
+type StreamScanner struct {
+	ctx       context.Context
+	scanner   *Scanner
+	processor findings.Processor
+	opts      StreamOptions
+
+	mu sync.Mutex
+	// buf holds input that has not been settled yet; offset is the stream
+	// position of buf[0], and line and column locate it.
+	buf    []byte
+	offset uint64
+	line   int
+	column int
+	closed bool
+}
+
+// NewStreamScanner returns a StreamScanner that reports findings to
+// processor, which may be nil when only the echoed output matters. Call Close
+// once the input is exhausted to scan what remains in the window.
+func NewStreamScanner(ctx context.Context, scanner *Scanner, processor findings.Processor, opts StreamOptions) *StreamScanner {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultStreamChunkSize
+	}
+	if opts.Overlap <= 0 {
+		opts.Overlap = DefaultStreamOverlap
+	}
+	if opts.Mask == nil {
+		opts.Mask = MaskPreservingLines
+	}
+	return &StreamScanner{
+		ctx:       ctx,
+		scanner:   scanner,
+		processor: processor,
+		opts:      opts,
+		buf:       make([]byte, 0, opts.ChunkSize+opts.Overlap),
+		line:      1,
+		column:    1,
+	}
+}
+
+// Write implements io.Writer.
+func (s *StreamScanner) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(p)
+}
+
+// writeLines writes p and then settles the window up to its last line break,
+// so complete lines are echoed without waiting for the window to fill.
+func (s *StreamScanner) writeLines(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.write(p)
+	if err != nil {
+		return n, err
+	}
+	if i := bytes.LastIndexByte(s.buf, '\n'); i >= 0 {
+		return n, s.settleTo(i + 1)
+	}
+	return n, nil
+}
+
+func (s *StreamScanner) write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("write to closed stream scanner")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := s.opts.ChunkSize + s.opts.Overlap - len(s.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		s.buf = append(s.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(s.buf) == s.opts.ChunkSize+s.opts.Overlap {
+			if err := s.settle(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// ReadFrom implements io.ReaderFrom, scanning r until EOF. Input is scanned as
+// it arrives rather than after r is exhausted: the complete lines of every
+// read are settled at once, the way RedactingWriter settles them, so a slow
+// producer's lines are reported and echoed as they are written. A token
+// spanning lines is therefore only detected if its lines arrive in one read.
+func (s *StreamScanner) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 32<<10)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			written, werr := s.writeLines(buf[:n])
+			total += int64(written)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Flush scans and echoes whatever input is in the window. A token that is
+// still being written when Flush is called will not be detected, so only flush
+// at a point where the input is known to be complete, such as the end of a
+// log entry.
+func (s *StreamScanner) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.settle(true)
+}
+
+// Close scans and echoes whatever input is left in the window.
+func (s *StreamScanner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.settle(true)
+}
+
+// settle scans the window, reports tokens starting in its settled prefix,
+// echoes that prefix and drops it. Unless final, the last Overlap bytes stay
+// unsettled; every token starting before them is therefore complete. The
+// prefix is then extended to the end of the last reported token, so a token is
+// never split between two windows.
+func (s *StreamScanner) settle(final bool) error {
+	limit := len(s.buf)
+	if !final {
+		limit -= s.opts.Overlap
+		// Prefer to cut at a line boundary, so the next window does not start
+		// in the middle of a word and echoed output is whole lines.
+		if i := bytes.LastIndexByte(s.buf[:limit], '\n'); i >= limit/2 {
+			limit = i + 1
+		}
+	}
+	return s.settleTo(limit)
+}
+
+// settleTo settles the window up to limit, or further if a token reported
+// before limit extends past it.
+func (s *StreamScanner) settleTo(limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	found, err := s.scanner.scanContent(s.ctx, BlobSHA(s.buf), s.buf)
+	if err != nil {
+		return err
+	}
+	var reported []*findings.Finding
+	for _, f := range found {
+		if f.Start >= uint64(limit) {
+			continue
+		}
+		reported = append(reported, f)
+	}
+	for _, f := range reported {
+		if int(f.End) > limit {
+			limit = int(f.End)
+		}
+	}
+
+	var out []byte
+	if s.opts.Echo != nil {
+		out = s.redact(limit, reported)
+	}
+	if s.processor != nil {
+		for _, f := range reported {
+			f.Path = s.opts.Path
+			f.Line, f.Column = s.locate(f.Start)
+			f.Snippet = findings.Snippet(f, s.buf, found)
+		}
+		// Filters see offsets relative to the window they are given as content;
+		// only what they keep is moved to stream offsets.
+		if s.opts.Filter != nil {
+			reported = s.opts.Filter.Filter(s.ctx, &findings.Blob{Path: s.opts.Path, Content: s.buf[:limit]}, reported)
+		}
+		for _, f := range reported {
+			f.Start, f.End = s.offset+f.Start, s.offset+f.End
+			f.Secret = append([]byte(nil), f.Secret...)
+			if err := s.processor.ProcessFinding(s.ctx, f); err != nil {
+				return err
+			}
+		}
+	}
+	if out != nil {
+		if _, err := s.opts.Echo.Write(out); err != nil {
+			return err
+		}
+	}
+
+	s.line, s.column = s.locate(uint64(limit))
+	s.offset += uint64(limit)
+	s.buf = append(s.buf[:0], s.buf[limit:]...)
+	return nil
+}
+
+// locate returns the stream line and column of offset within the window.
+func (s *StreamScanner) locate(offset uint64) (line, column int) {
+	line, column = findings.Locate(s.buf, offset)
+	if line == 1 {
+		column += s.column - 1
+	}
+	return line + s.line - 1, column
+}
+
+// redact returns buf[:limit] with the secret of every finding, whose offsets
+// are relative to the window, replaced by its mask. Overlapping findings are
+// merged and the whole merged span replaced once, using the mask of the
+// earliest, so no byte of either secret is echoed or dropped.
+func (s *StreamScanner) redact(limit int, found []*findings.Finding) []byte {
+	out := make([]byte, 0, limit)
+	pos := uint64(0)
+	for i := 0; i < len(found); {
+		merged := *found[i]
+		for i++; i < len(found) && found[i].Start < merged.End; i++ {
+			if found[i].End > merged.End {
+				merged.End = found[i].End
+			}
+		}
+		merged.Secret = s.buf[merged.Start:merged.End]
+		out = append(out, s.buf[pos:merged.Start]...)
+		out = append(out, s.opts.Mask(&merged)...)
+		pos = merged.End
+	}
+	return append(out, s.buf[pos:limit]...)
+}
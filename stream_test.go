@@ -0,0 +1,203 @@
+package hypercredscan
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/config"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// collectingProcessor records every finding it is handed.
+type collectingProcessor struct {
+	mu       sync.Mutex
+	findings []*findings.Finding
+}
+
+func (p *collectingProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.findings = append(p.findings, f)
+	return nil
+}
+
+func (p *collectingProcessor) Flush() error {
+	return nil
+}
+
+func TestStreamScannerBoundaries(t *testing.T) {
+	t.Parallel()
+	const token = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+	scanner, err := NewScanner(getConfig("ADAFRUIT_AIO_KEY"))
+	require.NoError(t, err)
+	defer scanner.Close()
+
+	// Place the token at every offset relative to a small window so it
+	// straddles a boundary in at least some of the runs.
+	for pad := 0; pad < 48; pad++ {
+		input := strings.Repeat("x", pad) + "\nkey: " + token + "\n" + strings.Repeat("log line\n", 20)
+
+		var echo bytes.Buffer
+		processor := &collectingProcessor{}
+		stream := NewStreamScanner(context.Background(), scanner, processor, StreamOptions{
+			Path:      "-",
+			ChunkSize: 40,
+			Overlap:   len(token) + 8,
+			Echo:      &echo,
+		})
+		// Feed one byte at a time to exercise partial writes.
+		for i := 0; i < len(input); i++ {
+			_, err := stream.Write([]byte{input[i]})
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.Close())
+
+		require.Len(t, processor.findings, 1, "pad %d", pad)
+		f := processor.findings[0]
+		require.Equal(t, token, string(f.Secret))
+		require.Equal(t, uint64(pad+6), f.Start)
+		require.Equal(t, 2, f.Line)
+		require.Equal(t, 6, f.Column)
+
+		expected := strings.Replace(input, token, strings.Repeat("*", len(token)), 1)
+		require.Equal(t, expected, echo.String())
+	}
+}
+
+// spanCheckingFilter records whether every finding's offsets point at its
+// secret within the content it is given.
+type spanCheckingFilter struct {
+	mismatches int
+}
+
+func (f *spanCheckingFilter) Name() string {
+	return "span-check"
+}
+
+func (f *spanCheckingFilter) Filter(_ context.Context, blob *findings.Blob, found []*findings.Finding) []*findings.Finding {
+	for _, finding := range found {
+		if finding.End > uint64(len(blob.Content)) || !bytes.Equal(blob.Content[finding.Start:finding.End], finding.Secret) {
+			f.mismatches++
+		}
+	}
+	return found
+}
+
+func TestStreamScannerFiltersWindowOffsets(t *testing.T) {
+	t.Parallel()
+	const token = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+	scanner, err := NewScanner(getConfig("ADAFRUIT_AIO_KEY"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	filter := &spanCheckingFilter{}
+	processor := &collectingProcessor{}
+	stream := NewStreamScanner(context.Background(), scanner, processor, StreamOptions{
+		ChunkSize: 64,
+		Overlap:   len(token) + 8,
+		Filter:    filter,
+	})
+	input := strings.Repeat("log line\n", 30) + "key: " + token + "\n" + strings.Repeat("log line\n", 30) + "key: " + token + "\n"
+	_, err = stream.Write([]byte(input))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	require.Zero(t, filter.mismatches)
+	require.Len(t, processor.findings, 2)
+	for _, f := range processor.findings {
+		require.Equal(t, token, input[f.Start:f.End], "reported offsets are stream offsets")
+	}
+}
+
+// overlappingScanner returns a scanner whose two providers match overlapping
+// spans spanning lines in "tok_abc\ndef\nghi_end".
+func overlappingScanner(t *testing.T) *Scanner {
+	t.Helper()
+	cfg, err := config.LoadCustomConfig([]*config.ProviderConfig{
+		{Name: "ADAFRUIT_AIO_KEY", Pattern: `tok_[a-z]+\n[a-z]+`},
+		{Name: "AWS_KEYID", Pattern: `[a-z]+\n[a-z]+_end`},
+	})
+	require.NoError(t, err)
+	scanner, err := NewScanner(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+	return scanner
+}
+
+func TestStreamScannerMasksOverlappingFindings(t *testing.T) {
+	t.Parallel()
+	scanner := overlappingScanner(t)
+
+	var echo bytes.Buffer
+	processor := &collectingProcessor{}
+	stream := NewStreamScanner(context.Background(), scanner, processor, StreamOptions{Echo: &echo})
+	_, err := stream.Write([]byte("x tok_abc\ndef\nghi_end y\n"))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	require.Len(t, processor.findings, 2)
+	require.Equal(t, "x *******\n***\n******* y\n", echo.String(), "the merged span keeps its line breaks")
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer and a reader on different
+// goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamScannerReadFromEchoesLinesAsTheyArrive(t *testing.T) {
+	t.Parallel()
+	const token = "aio_FMBo07xPM4e0Aj3eYjO23blItBvS"
+
+	scanner, err := NewScanner(getConfig("ADAFRUIT_AIO_KEY"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, scanner.Close()) })
+
+	echo := &syncBuffer{}
+	processor := &collectingProcessor{}
+	stream := NewStreamScanner(context.Background(), scanner, processor, StreamOptions{Echo: echo})
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := stream.ReadFrom(r)
+		done <- err
+	}()
+
+	masked := strings.Repeat("*", len(token))
+	_, err = io.WriteString(w, "step 1\nkey: "+token+"\nstep")
+	require.NoError(t, err)
+	// Far less than a window has been written, but the complete lines are out.
+	require.Eventually(t, func() bool {
+		return echo.String() == "step 1\nkey: "+masked+"\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = io.WriteString(w, " 2\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, <-done)
+	require.NoError(t, stream.Close())
+	require.Equal(t, "step 1\nkey: "+masked+"\nstep 2\n", echo.String())
+	require.Len(t, processor.findings, 1)
+}
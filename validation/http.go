@@ -0,0 +1,251 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// maxDrainBytes bounds how much of a response body is read before closing it,
+// so connections can be reused without reading arbitrarily large responses.
+const maxDrainBytes = 64 << 10
+
+// maxRetryAfter is the longest Retry-After a check waits for. Vendors asking
+// for longer are given up on rather than stalling validation.
+const maxRetryAfter = 30 * time.Second
+
+// HTTPCheck describes a vendor endpoint that accepts live secrets and rejects
+// revoked ones. Any 2xx response means the secret is active and a status in
+// Inactive means it is not; other statuses are inconclusive.
+type HTTPCheck struct {
+	// BaseURL is the scheme and host of the vendor API. Override it to send
+	// checks through a proxy or to a mock server.
+	BaseURL string
+	// Method defaults to GET.
+	Method string
+	Path   string
+	// Header names the request header carrying the secret, and Format its
+	// value, with %s standing for the secret. Format defaults to "%s".
+	Header string
+	Format string
+	// Inactive lists the statuses meaning the secret was rejected. It
+	// defaults to 401.
+	Inactive []int
+}
+
+// DefaultHTTPChecks returns checks for the vendors with an endpoint that
+// authenticates a secret without side effects, keyed by provider name. The
+// map is a fresh copy the caller may modify.
+func DefaultHTTPChecks() map[string]HTTPCheck {
+	github := HTTPCheck{BaseURL: "https://api.github.com", Path: "/rate_limit", Header: "Authorization", Format: "token %s"}
+	stripe := HTTPCheck{BaseURL: "https://api.stripe.com", Path: "/v1/balance", Header: "Authorization", Format: "Bearer %s"}
+	return map[string]HTTPCheck{
+		"DIGITALOCEAN_PERSONAL_ACCESS_TOKEN": {BaseURL: "https://api.digitalocean.com", Path: "/v2/account", Header: "Authorization", Format: "Bearer %s"},
+		"GITHUB_OAUTH_ACCESS_TOKEN":          github,
+		"GITHUB_PERSONAL_ACCESS_TOKEN":       github,
+		"GITHUB_SERVER_TO_SERVER_TOKEN":      github,
+		"GITHUB_USER_TO_SERVER_TOKEN":        github,
+		"GITLAB_ACCESS_TOKEN":                {BaseURL: "https://gitlab.com", Path: "/api/v4/personal_access_tokens/self", Header: "PRIVATE-TOKEN"},
+		"NPM_TOKEN":                          {BaseURL: "https://registry.npmjs.org", Path: "/-/whoami", Header: "Authorization", Format: "Bearer %s"},
+		"OPENAI_API_KEY":                     {BaseURL: "https://api.openai.com", Path: "/v1/models", Header: "Authorization", Format: "Bearer %s"},
+		"SENDGRID_API_KEY":                   {BaseURL: "https://api.sendgrid.com", Path: "/v3/scopes", Header: "Authorization", Format: "Bearer %s"},
+		"STRIPE_LIVE_API_SECRET_KEY":         stripe,
+		"STRIPE_TEST_API_SECRET_KEY":         stripe,
+	}
+}
+
+// HTTPOptions tunes how HTTP validators talk to vendors. The zero value makes
+// a single attempt per check with no rate limit and no circuit breaker.
+type HTTPOptions struct {
+	// Client defaults to a client with a 10 second timeout.
+	Client *http.Client
+	// RateLimit is the number of requests per second allowed to each vendor,
+	// with bursts of up to Burst. Zero disables rate limiting.
+	RateLimit float64
+	Burst     int
+	// MaxRetries is how often a check is retried after a network error, a 429
+	// or a 5xx response. Retries wait RetryBackoff, doubling each time, or
+	// as long as a Retry-After header asks if that is longer.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// BreakerThreshold is the number of consecutive failed checks after which
+	// checks against the vendor are rejected with ErrCircuitOpen for
+	// BreakerCooldown. Zero disables the circuit breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultHTTPOptions returns options suited to checking secrets against
+// production vendor APIs.
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		RateLimit:        5,
+		Burst:            5,
+		MaxRetries:       2,
+		RetryBackoff:     500 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+// HTTPValidator validates secrets with an HTTPCheck.
+type HTTPValidator struct {
+	check        HTTPCheck
+	endpoint     string
+	client       *http.Client
+	limiter      *rateLimiter
+	breaker      *breaker
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// NewHTTPValidator returns a validator performing check, with its own rate
+// limit and circuit breaker.
+func NewHTTPValidator(check HTTPCheck, opts HTTPOptions) (*HTTPValidator, error) {
+	return newHTTPValidator(check, opts, newRateLimiter(opts.RateLimit, opts.Burst), newBreaker(opts.BreakerThreshold, opts.BreakerCooldown))
+}
+
+func newHTTPValidator(check HTTPCheck, opts HTTPOptions, limiter *rateLimiter, breaker *breaker) (*HTTPValidator, error) {
+	base, err := url.Parse(check.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q is not an http or https URL", check.BaseURL)
+	}
+	if check.Header == "" {
+		return nil, fmt.Errorf("check against %s names no header to carry the secret", check.BaseURL)
+	}
+	if check.Method == "" {
+		check.Method = http.MethodGet
+	}
+	if check.Format == "" {
+		check.Format = "%s"
+	}
+	if len(check.Inactive) == 0 {
+		check.Inactive = []int{http.StatusUnauthorized}
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPValidator{
+		check:        check,
+		endpoint:     strings.TrimSuffix(check.BaseURL, "/") + check.Path,
+		client:       client,
+		limiter:      limiter,
+		breaker:      breaker,
+		maxRetries:   opts.MaxRetries,
+		retryBackoff: opts.RetryBackoff,
+	}, nil
+}
+
+// NewHTTPRegistry returns a registry with a validator for each check. Checks
+// against the same base URL share a rate limit and circuit breaker, as they
+// reach the same vendor.
+func NewHTTPRegistry(checks map[string]HTTPCheck, opts HTTPOptions) (*Registry, error) {
+	type vendor struct {
+		limiter *rateLimiter
+		breaker *breaker
+	}
+	vendors := make(map[string]vendor)
+	registry := NewRegistry()
+	for provider, check := range checks {
+		v, ok := vendors[check.BaseURL]
+		if !ok {
+			v = vendor{limiter: newRateLimiter(opts.RateLimit, opts.Burst), breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown)}
+			vendors[check.BaseURL] = v
+		}
+		validator, err := newHTTPValidator(check, opts, v.limiter, v.breaker)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", provider, err)
+		}
+		registry.Register(provider, validator)
+	}
+	return registry, nil
+}
+
+// Validate implements Validator.
+func (v *HTTPValidator) Validate(ctx context.Context, secret []byte) (Result, error) {
+	if !v.breaker.allow() {
+		return Result{}, ErrCircuitOpen
+	}
+	result, err := v.validate(ctx, secret)
+	// A check abandoned by its caller says nothing about the vendor.
+	v.breaker.record(err == nil || ctx.Err() != nil)
+	return result, err
+}
+
+func (v *HTTPValidator) validate(ctx context.Context, secret []byte) (Result, error) {
+	backoff := v.retryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := v.limiter.wait(ctx); err != nil {
+			return Result{}, err
+		}
+		status, retryAfter, err := v.do(ctx, secret)
+		if err == nil && status != http.StatusTooManyRequests && status < 500 {
+			return v.classify(status), nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s responded with status %d", v.endpoint, status)
+		}
+		if attempt >= v.maxRetries || retryAfter > maxRetryAfter || ctx.Err() != nil {
+			return Result{}, err
+		}
+
+		delay := backoff
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		backoff *= 2
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return Result{}, ctx.Err()
+		}
+	}
+}
+
+// do makes a single request, returning the response status and how long the
+// vendor asked to wait before retrying.
+func (v *HTTPValidator) do(ctx context.Context, secret []byte) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, v.check.Method, v.endpoint, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set(v.check.Header, fmt.Sprintf(v.check.Format, secret))
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return resp.StatusCode, retryAfter, nil
+}
+
+func (v *HTTPValidator) classify(status int) Result {
+	detail := "status " + strconv.Itoa(status)
+	if status >= 200 && status < 300 {
+		return Result{Status: findings.ValidationActive, Detail: detail}
+	}
+	for _, inactive := range v.check.Inactive {
+		if status == inactive {
+			return Result{Status: findings.ValidationInactive, Detail: detail}
+		}
+	}
+	return Result{Status: findings.ValidationUnknown, Detail: "unexpected " + detail}
+}
@@ -0,0 +1,61 @@
+package validation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+	l := newRateLimiter(10, 2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	// The burst is available immediately.
+	require.NoError(t, l.wait(context.Background()))
+	require.NoError(t, l.wait(context.Background()))
+
+	// The next token is 100ms away, longer than the caller will wait.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.wait(ctx), context.DeadlineExceeded)
+
+	// The abandoned wait gave its token back.
+	now = now.Add(100 * time.Millisecond)
+	require.NoError(t, l.wait(context.Background()))
+
+	require.Nil(t, newRateLimiter(0, 5))
+	require.NoError(t, (*rateLimiter)(nil).wait(context.Background()))
+}
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+	b := newBreaker(2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	require.True(t, b.allow())
+	b.record(false)
+	require.True(t, b.allow())
+	b.record(false)
+	require.False(t, b.allow())
+
+	// One probe after the cooldown; a failed probe reopens the circuit.
+	now = now.Add(time.Minute)
+	require.True(t, b.allow())
+	require.False(t, b.allow())
+	b.record(false)
+	require.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	require.True(t, b.allow())
+	b.record(true)
+	require.True(t, b.allow())
+	require.True(t, b.allow())
+
+	require.Nil(t, newBreaker(0, time.Minute))
+	require.True(t, (*breaker)(nil).allow())
+}
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// ProcessorOptions tunes a Processor. Zero fields take the defaults noted.
+type ProcessorOptions struct {
+	// Workers is the number of checks run concurrently. Defaults to 4.
+	Workers int
+	// QueueSize is the number of findings that may wait for a worker.
+	// Findings arriving while the queue is full are passed on with an unknown
+	// status rather than holding up the scan. Defaults to 1024.
+	QueueSize int
+	// Timeout bounds each check. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Processor validates findings whose provider has a validator before passing
+// them on to another processor, and passes other findings on directly.
+// Validation happens on background workers, so ProcessFinding never waits on
+// a vendor; findings reach the next processor as their checks complete, and
+// Flush waits for outstanding checks. A secret found several times is checked
+// once.
+type Processor struct {
+	ctx      context.Context
+	next     findings.Processor
+	registry *Registry
+	timeout  time.Duration
+	now      func() time.Time
+
+	queue     chan *findings.Finding
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	cache map[string]*cachedValidation
+	err   error
+}
+
+// cachedValidation is the validation of a fingerprint, available once done is
+// closed.
+type cachedValidation struct {
+	done       chan struct{}
+	validation findings.Validation
+}
+
+// NewProcessor returns a Processor validating findings with the validators in
+// registry and passing them on to next. Checks stop when ctx is done.
+func NewProcessor(ctx context.Context, next findings.Processor, registry *Registry, opts ProcessorOptions) *Processor {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	p := &Processor{
+		ctx:      ctx,
+		next:     next,
+		registry: registry,
+		timeout:  opts.Timeout,
+		now:      time.Now,
+		queue:    make(chan *findings.Finding, opts.QueueSize),
+		cache:    make(map[string]*cachedValidation),
+	}
+	p.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// ProcessFinding implements findings.Processor.
+func (p *Processor) ProcessFinding(ctx context.Context, f *findings.Finding) error {
+	if _, ok := p.registry.Lookup(f.Provider); !ok {
+		return p.next.ProcessFinding(ctx, f)
+	}
+	select {
+	case p.queue <- f:
+		return nil
+	default:
+		f.Validation = &findings.Validation{Status: findings.ValidationUnknown, Detail: "validation queue full", CheckedAt: p.now()}
+		return p.next.ProcessFinding(ctx, f)
+	}
+}
+
+// Flush implements findings.Processor. It waits for outstanding checks before
+// flushing the next processor.
+func (p *Processor) Flush() error {
+	p.closeOnce.Do(func() {
+		close(p.queue)
+	})
+	p.wg.Wait()
+	err := p.next.Flush()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	return err
+}
+
+func (p *Processor) work() {
+	defer p.wg.Done()
+	for f := range p.queue {
+		validation := p.validate(f)
+		f.Validation = &validation
+		if err := p.next.ProcessFinding(p.ctx, f); err != nil {
+			p.mu.Lock()
+			if p.err == nil {
+				p.err = err
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// validate checks f's secret, or waits for the result of checking it if
+// another worker already is.
+func (p *Processor) validate(f *findings.Finding) findings.Validation {
+	if f.Fingerprint == "" {
+		return p.check(f)
+	}
+	p.mu.Lock()
+	cached, ok := p.cache[f.Fingerprint]
+	if !ok {
+		cached = &cachedValidation{done: make(chan struct{})}
+		p.cache[f.Fingerprint] = cached
+	}
+	p.mu.Unlock()
+	if ok {
+		<-cached.done
+		return cached.validation
+	}
+	cached.validation = p.check(f)
+	close(cached.done)
+	return cached.validation
+}
+
+func (p *Processor) check(f *findings.Finding) findings.Validation {
+	validator, _ := p.registry.Lookup(f.Provider)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	defer cancel()
+	result, err := validator.Validate(ctx, f.Secret)
+	if err != nil {
+		return findings.Validation{Status: findings.ValidationError, Detail: err.Error(), CheckedAt: p.now()}
+	}
+	if result.Status == "" {
+		result.Status = findings.ValidationUnknown
+	}
+	return findings.Validation{Status: result.Status, Detail: result.Detail, CheckedAt: p.now()}
+}
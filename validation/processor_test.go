@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/validation/vendormock"
+)
+
+type collectingProcessor struct {
+	mu       sync.Mutex
+	findings []*findings.Finding
+	flushed  bool
+	err      error
+}
+
+func (c *collectingProcessor) ProcessFinding(_ context.Context, f *findings.Finding) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.findings = append(c.findings, f)
+	return c.err
+}
+
+func (c *collectingProcessor) Flush() error {
+	c.flushed = true
+	return nil
+}
+
+func TestProcessorDoesNotBlock(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	registry := NewRegistry()
+	registry.Register("SLOW", ValidatorFunc(func(ctx context.Context, _ []byte) (Result, error) {
+		<-release
+		return Result{Status: findings.ValidationActive}, nil
+	}))
+	next := &collectingProcessor{}
+	p := NewProcessor(context.Background(), next, registry, ProcessorOptions{Workers: 1})
+
+	slow := &findings.Finding{Provider: "SLOW", Fingerprint: "a"}
+	other := &findings.Finding{Provider: "OTHER", Fingerprint: "b"}
+	require.NoError(t, p.ProcessFinding(context.Background(), slow))
+	require.NoError(t, p.ProcessFinding(context.Background(), other))
+
+	// The finding without a validator is passed on while the check is still
+	// running.
+	next.mu.Lock()
+	require.Equal(t, []*findings.Finding{other}, next.findings)
+	next.mu.Unlock()
+	require.Nil(t, other.Validation)
+
+	close(release)
+	require.NoError(t, p.Flush())
+	require.True(t, next.flushed)
+	require.Len(t, next.findings, 2)
+	require.Equal(t, findings.ValidationActive, slow.Validation.Status)
+	require.False(t, slow.Validation.CheckedAt.IsZero())
+}
+
+func TestProcessorChecksEachSecretOnce(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	registry := NewRegistry()
+	registry.Register("P", ValidatorFunc(func(ctx context.Context, _ []byte) (Result, error) {
+		calls.Add(1)
+		return Result{Status: findings.ValidationInactive, Detail: "revoked"}, nil
+	}))
+	next := &collectingProcessor{}
+	p := NewProcessor(context.Background(), next, registry, ProcessorOptions{Workers: 4})
+	for i := 0; i < 20; i++ {
+		require.NoError(t, p.ProcessFinding(context.Background(), &findings.Finding{Provider: "P", Fingerprint: "same"}))
+	}
+	require.NoError(t, p.Flush())
+	require.Equal(t, int32(1), calls.Load())
+	require.Len(t, next.findings, 20)
+	for _, f := range next.findings {
+		require.Equal(t, findings.ValidationInactive, f.Validation.Status)
+		require.Equal(t, "revoked", f.Validation.Detail)
+	}
+}
+
+func TestProcessorQueueFull(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	registry := NewRegistry()
+	registry.Register("P", ValidatorFunc(func(ctx context.Context, _ []byte) (Result, error) {
+		started <- struct{}{}
+		<-release
+		return Result{Status: findings.ValidationActive}, nil
+	}))
+	next := &collectingProcessor{}
+	p := NewProcessor(context.Background(), next, registry, ProcessorOptions{Workers: 1, QueueSize: 1})
+
+	busy := &findings.Finding{Provider: "P", Fingerprint: "busy"}
+	queued := &findings.Finding{Provider: "P", Fingerprint: "queued"}
+	overflow := &findings.Finding{Provider: "P", Fingerprint: "overflow"}
+	require.NoError(t, p.ProcessFinding(context.Background(), busy))
+	<-started
+	require.NoError(t, p.ProcessFinding(context.Background(), queued))
+	require.NoError(t, p.ProcessFinding(context.Background(), overflow))
+	require.Equal(t, findings.ValidationUnknown, overflow.Validation.Status)
+
+	close(release)
+	<-started
+	require.NoError(t, p.Flush())
+	require.Equal(t, findings.ValidationActive, queued.Validation.Status)
+}
+
+func TestProcessorErrors(t *testing.T) {
+	t.Parallel()
+	server := vendormock.NewServer()
+	defer server.Close()
+	registry, err := NewHTTPRegistry(mockChecks(server), HTTPOptions{})
+	require.NoError(t, err)
+	next := &collectingProcessor{err: errors.New("disk full")}
+	p := NewProcessor(context.Background(), next, registry, ProcessorOptions{})
+
+	server.Fail(500)
+	f := &findings.Finding{Provider: "NPM_TOKEN", Secret: []byte("npm_x"), Fingerprint: "x"}
+	require.NoError(t, p.ProcessFinding(context.Background(), f))
+	require.EqualError(t, p.Flush(), "disk full")
+	require.Equal(t, findings.ValidationError, f.Validation.Status)
+	require.Contains(t, f.Validation.Detail, "status 500")
+}
@@ -0,0 +1,74 @@
+// Package validation checks whether detected secrets are still live by asking
+// the vendors that issued them. Checks run off the scan path: Processor
+// validates findings in the background before handing them on.
+package validation
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/github/token-scanning-service/hypercredscan/hypercredscan/findings"
+)
+
+// Validator checks a secret with its vendor. Validate returns an error only
+// when the check could not be completed; a vendor rejecting the secret is an
+// inactive result, not an error.
+type Validator interface {
+	Validate(ctx context.Context, secret []byte) (Result, error)
+}
+
+// Result is the outcome of a completed check.
+type Result struct {
+	Status findings.ValidationStatus
+	// Detail explains the status, for example the response code that led to
+	// it.
+	Detail string
+}
+
+// ValidatorFunc adapts a function to the Validator interface.
+type ValidatorFunc func(ctx context.Context, secret []byte) (Result, error)
+
+// Validate implements Validator.
+func (f ValidatorFunc) Validate(ctx context.Context, secret []byte) (Result, error) {
+	return f(ctx, secret)
+}
+
+// Registry maps provider names to the validators that check their secrets. A
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	validators map[string]Validator
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{validators: make(map[string]Validator)}
+}
+
+// Register sets the validator for provider, replacing any previous one.
+func (r *Registry) Register(provider string, v Validator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validators[provider] = v
+}
+
+// Lookup returns the validator for provider.
+func (r *Registry) Lookup(provider string) (Validator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.validators[provider]
+	return v, ok
+}
+
+// Providers returns the names of the providers with a validator, sorted.
+func (r *Registry) Providers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.validators))
+	for name := range r.validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
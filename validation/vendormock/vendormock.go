@@ -0,0 +1,136 @@
+// Package vendormock emulates the endpoints vendors offer for checking
+// whether a secret is live, so validation can be tested without reaching
+// real vendors or holding real secrets.
+package vendormock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpoint is a vendor check endpoint: a request to Path carrying a secret in
+// Header, after Prefix, is answered 200 if the secret is active and 401
+// otherwise.
+type endpoint struct {
+	vendor string
+	path   string
+	header string
+	prefix string
+}
+
+// endpoints are the vendor APIs the mock emulates. All of them are served
+// from the same host, as distinct paths.
+var endpoints = []endpoint{
+	{vendor: "digitalocean", path: "/v2/account", header: "Authorization", prefix: "Bearer "},
+	{vendor: "github", path: "/rate_limit", header: "Authorization", prefix: "token "},
+	{vendor: "gitlab", path: "/api/v4/personal_access_tokens/self", header: "PRIVATE-TOKEN"},
+	{vendor: "npm", path: "/-/whoami", header: "Authorization", prefix: "Bearer "},
+	{vendor: "openai", path: "/v1/models", header: "Authorization", prefix: "Bearer "},
+	{vendor: "sendgrid", path: "/v3/scopes", header: "Authorization", prefix: "Bearer "},
+	{vendor: "stripe", path: "/v1/balance", header: "Authorization", prefix: "Bearer "},
+}
+
+// Server is a local HTTP server emulating vendor check endpoints. Secrets are
+// rejected unless activated, and failures and slowdowns can be injected. A
+// Server is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	active   map[string]bool
+	failures []int
+	delay    time.Duration
+	requests map[string]int
+}
+
+// NewServer starts a Server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{active: make(map[string]bool), requests: make(map[string]int)}
+	mux := http.NewServeMux()
+	for _, e := range endpoints {
+		e := e
+		mux.HandleFunc(e.path, func(w http.ResponseWriter, r *http.Request) {
+			s.serve(w, r, e)
+		})
+	}
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Activate makes the server accept secrets.
+func (s *Server) Activate(secrets ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, secret := range secrets {
+		s.active[secret] = true
+	}
+}
+
+// Revoke makes the server reject secrets.
+func (s *Server) Revoke(secrets ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, secret := range secrets {
+		delete(s.active, secret)
+	}
+}
+
+// Fail makes the server answer the next len(statuses) requests with statuses,
+// in order, whatever they ask for.
+func (s *Server) Fail(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// SetDelay makes the server wait d before answering each request.
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Requests returns the number of requests the server received for vendor,
+// such as "github".
+func (s *Server) Requests(vendor string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[vendor]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, e endpoint) {
+	s.mu.Lock()
+	s.requests[e.vendor]++
+	delay := s.delay
+	status := 0
+	if len(s.failures) > 0 {
+		status, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	value := r.Header.Get(e.header)
+	s.mu.Lock()
+	active := strings.HasPrefix(value, e.prefix) && s.active[strings.TrimPrefix(value, e.prefix)]
+	s.mu.Unlock()
+	if !active {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{}`))
+}